@@ -0,0 +1,71 @@
+package preport
+
+import (
+	"time"
+)
+
+// BusinessHours are the working hours of a team, used to measure durations in
+// working time only.
+type BusinessHours struct {
+	// Location is the time zone the working hours are in. It defaults to UTC.
+	Location *time.Location
+	Weekdays []time.Weekday
+	// Start and End are the offsets from midnight at which a working day
+	// starts and ends.
+	Start, End time.Duration
+	// Holidays are the days not worked on. Only their date is used.
+	Holidays []time.Time
+}
+
+// Between returns the working time between from and to, which is zero when to
+// is before from.
+func (b BusinessHours) Between(from, to time.Time) time.Duration {
+	loc := b.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	from, to = from.In(loc), to.In(loc)
+
+	var d time.Duration
+	y, m, day := from.Date()
+	for date := time.Date(y, m, day, 0, 0, 0, 0, loc); date.Before(to); date = date.AddDate(0, 0, 1) {
+		if !b.isWorkday(date) {
+			continue
+		}
+
+		start, end := wallClock(date, b.Start), wallClock(date, b.End)
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			d += end.Sub(start)
+		}
+	}
+	return d
+}
+
+// wallClock returns the time offset from midnight on date, as shown on the
+// wall clock, so that working days keep their hours on days switching to or
+// from daylight saving time.
+func wallClock(date time.Time, offset time.Duration) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d, 0, 0, int(offset/time.Second), 0, date.Location())
+}
+
+func (b BusinessHours) isWorkday(date time.Time) bool {
+	y, m, d := date.Date()
+	for _, h := range b.Holidays {
+		if hy, hm, hd := h.Date(); hy == y && hm == m && hd == d {
+			return false
+		}
+	}
+	for _, wd := range b.Weekdays {
+		if wd == date.Weekday() {
+			return true
+		}
+	}
+	return false
+}
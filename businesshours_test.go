@@ -0,0 +1,75 @@
+package preport_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport"
+)
+
+func TestBusinessHours_Between(t *testing.T) {
+	ams, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	bh := preport.BusinessHours{
+		Location: ams,
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Start:    9 * time.Hour,
+		End:      17 * time.Hour,
+		Holidays: []time.Time{time.Date(2021, 12, 27, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range []struct {
+		name     string
+		from, to time.Time
+		want     time.Duration
+	}{
+		{
+			name: "Same day",
+			from: time.Date(2021, 12, 1, 10, 0, 0, 0, ams),
+			to:   time.Date(2021, 12, 1, 12, 30, 0, 0, ams),
+			want: 150 * time.Minute,
+		},
+		{
+			name: "Outside working hours",
+			from: time.Date(2021, 12, 1, 7, 0, 0, 0, ams),
+			to:   time.Date(2021, 12, 1, 20, 0, 0, 0, ams),
+			want: 8 * time.Hour,
+		},
+		{
+			name: "Friday evening to Monday morning",
+			from: time.Date(2021, 12, 3, 18, 0, 0, 0, ams),
+			to:   time.Date(2021, 12, 6, 10, 0, 0, 0, ams),
+			want: time.Hour,
+		},
+		{
+			name: "Holiday",
+			from: time.Date(2021, 12, 24, 16, 0, 0, 0, ams),
+			to:   time.Date(2021, 12, 28, 10, 0, 0, 0, ams),
+			want: 2 * time.Hour,
+		},
+		{
+			name: "Other time zone",
+			from: time.Date(2021, 12, 1, 8, 0, 0, 0, time.UTC),
+			to:   time.Date(2021, 12, 1, 9, 0, 0, 0, time.UTC),
+			want: time.Hour,
+		},
+		{
+			name: "Switch to daylight saving time",
+			from: time.Date(2021, 3, 26, 16, 0, 0, 0, ams),
+			to:   time.Date(2021, 3, 29, 10, 0, 0, 0, ams),
+			want: 2 * time.Hour,
+		},
+		{
+			name: "Reversed",
+			from: time.Date(2021, 12, 2, 10, 0, 0, 0, ams),
+			to:   time.Date(2021, 12, 1, 10, 0, 0, 0, ams),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, bh.Between(tc.from, tc.to))
+		})
+	}
+}
@@ -3,12 +3,14 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"sort"
+	"net/url"
 	"strings"
 	"text/template"
+	"time"
 
 	"github.com/epels/preport"
 	"github.com/epels/preport/notifier"
@@ -16,89 +18,602 @@ import (
 )
 
 type notifierConfig struct {
-	Notifiers []struct {
-		Channel  string
-		Projects []string
+	Notifiers []notifierEntry
+}
+
+type notifierEntry struct {
+	// Notifier is the name of the notifier delivering the report to Channel.
+	// It defaults to Slack. For Teams and webhooks, Channel is the URL to post
+	// the report to, and for email a comma separated list of recipients.
+	Notifier string
+	Channel  string
+	Projects []string
+	Groups   []groupEntry
+	// Filter narrows down the pull requests listed for Projects and Groups.
+	// Without it, defaultFilter is used.
+	Filter *preport.Filter
+	// Template overrides the report template for this entry, e.g. to render
+	// a JSON body for a webhook.
+	Template string
+	// SkipEmpty skips notifying when there are no pull requests to report.
+	SkipEmpty bool
+	// EmptyTemplate is rendered instead of the report template when there are
+	// no pull requests to report, e.g. to send an "all clear" message.
+	EmptyTemplate string
+	// TemplateData overrides the data the templates of this entry are
+	// rendered with: templateDataPullRequests or templateDataReport.
+	TemplateData string
+	// Sort is the order in which pull requests are reported: sortCreated, the
+	// default, sortUpdated or sortWaiting.
+	Sort string
+	// Assign assigns a reviewer from a pool to every listed pull request
+	// without one. Without it, no reviewers are assigned.
+	Assign *assignEntry
+	// Blocks posts reports to Slack as Block Kit blocks, e.g. rendered by
+	// slackBlocks, rather than as mrkdwn.
+	Blocks bool
+	// Headers are set on every request to the webhook, and Secret signs their
+	// bodies. Both belong to this entry only, so that credentials are never
+	// sent to the endpoints of other entries.
+	Headers map[string]string
+	Secret  string
+
+	tmpl, emptyTmpl *template.Template
+	// webhook delivers the reports of entries with headers or a secret.
+	webhook preport.Notifier
+}
+
+type groupEntry struct {
+	// Group refers to a group like projects refer to a project, e.g.
+	// "gitlab:my-group".
+	Group string
+	// Exclude lists the full paths of projects and subgroups to leave out.
+	Exclude []string
+}
+
+// excludes reports whether the project at path is excluded from the group,
+// either directly or through one of its parent groups.
+func (g groupEntry) excludes(path string) bool {
+	for _, e := range g.Exclude {
+		if path == e || strings.HasPrefix(path, e+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the options of every entry, so that mistakes surface at
+// startup rather than when reporting.
+func (c *notifierConfig) validate() error {
+	for i := range c.Notifiers {
+		n := &c.Notifiers[i]
+
+		if err := validateTemplateData(n.TemplateData); err != nil {
+			return fmt.Errorf("%s: %s", n.displayChannel(), err)
+		}
+		if err := validateSort(n.Sort); err != nil {
+			return fmt.Errorf("%s: %s", n.displayChannel(), err)
+		}
+		if n.Blocks && n.Notifier != "" && n.Notifier != notifierSlack {
+			return fmt.Errorf("%s: blocks are only supported by Slack", n.displayChannel())
+		}
+		if n.Headers != nil || n.Secret != "" {
+			if n.Notifier != notifierWebhook {
+				return fmt.Errorf("%s: headers and secret are only supported by webhooks", n.displayChannel())
+			}
+			wh, err := notifier.NewWebhook(n.Headers, n.Secret)
+			if err != nil {
+				return fmt.Errorf("notifier: NewWebhook: %s", err)
+			}
+			n.webhook = wh
+		}
+	}
+	return nil
+}
+
+// parseTemplates parses the templates of every entry using funcs, falling back
+// to tmpl for entries without a template of their own.
+func (c *notifierConfig) parseTemplates(tmpl *template.Template, funcs template.FuncMap) error {
+	for i := range c.Notifiers {
+		n := &c.Notifiers[i]
+
+		n.tmpl = tmpl
+		if n.Template != "" {
+			t, err := newTemplate(n.displayChannel(), n.Template, funcs)
+			if err != nil {
+				return fmt.Errorf("text/template: Template.Parse: %s", err)
+			}
+			n.tmpl = t
+		}
+		if n.EmptyTemplate != "" {
+			t, err := newTemplate(n.displayChannel()+" (empty)", n.EmptyTemplate, funcs)
+			if err != nil {
+				return fmt.Errorf("text/template: Template.Parse: %s", err)
+			}
+			n.emptyTmpl = t
+		}
 	}
+	return nil
 }
 
-func run(ctx context.Context, genConf generalConfig, stderr io.Writer) error {
-	errLog := log.New(stderr, "", log.LstdFlags|log.Lshortfile)
+// app reports the pull requests listed by its providers to its notifiers, both
+// of which are keyed by the name used to refer to them in configuration.
+type app struct {
+	listers   map[string]preport.PullRequestLister
+	notifiers map[string]preport.Notifier
+	errLog    *log.Logger
 
+	// chatIDs maps users, keyed by userKey, to their chat member ID, as users
+	// of different providers may share a username. Users missing from it
+	// are looked up by email address through userLookup, if set.
+	chatIDs    map[string]string
+	userLookup userLookup
+	// suggestOwners sets the suggested owners of pull requests listed by
+	// providers implementing preport.OwnerSuggester.
+	suggestOwners bool
+	// dryRun chooses reviewers to assign without actually assigning them.
+	dryRun bool
+	// assignState is the path of the file keeping the round-robin position
+	// of every pool between runs, if any.
+	assignState string
+	// now is the moment ages in reports are measured at.
+	now time.Time
+	// templateData is the data templates are rendered with, unless overridden
+	// by their entry.
+	templateData string
+}
+
+// userLookup looks up chat member IDs by email address.
+type userLookup interface {
+	LookupUserByEmail(ctx context.Context, email string) (string, error)
+}
+
+// run reports once, measuring ages in reports up to now.
+func run(ctx context.Context, genConf generalConfig, now time.Time, stdout, stderr io.Writer) error {
 	var notConf notifierConfig
 	if err := json.Unmarshal([]byte(genConf.NotifierConfig), &notConf); err != nil {
 		return fmt.Errorf("encoding/json: Unmarshal: %s", err)
 	}
-	tmpl, err := template.New("pullrequests").Parse(genConf.ReportTemplate)
+	if err := validateTemplateData(genConf.TemplateData); err != nil {
+		return err
+	}
+	if err := notConf.validate(); err != nil {
+		return err
+	}
+	bh, err := genConf.BusinessHours.parse()
+	if err != nil {
+		return err
+	}
+	funcs := templateFuncs(bh, now)
+	tmpl, err := newTemplate("pullrequests", genConf.ReportTemplate, funcs)
 	if err != nil {
 		return fmt.Errorf("text/template: Template.Parse: %s", err)
 	}
+	if err := notConf.parseTemplates(tmpl, funcs); err != nil {
+		return err
+	}
 
-	sc, err := notifier.NewSlack(genConf.Slack.BaseURL, genConf.Slack.Bearer)
+	a, err := newApp(genConf, stdout, stderr)
 	if err != nil {
-		return fmt.Errorf("notifier: NewSlack: %s", err)
+		return err
 	}
-	gc, err := vcs.NewGitlab(genConf.Gitlab.BaseURL, genConf.Gitlab.Bearer)
-	if err != nil {
-		return fmt.Errorf("vcs: NewGitlab: %s", err)
+	if err := a.checkConfig(notConf); err != nil {
+		return err
 	}
+	a.now = now
+	a.report(ctx, notConf)
+	return nil
+}
 
-	// First, create a flat map of projects and fetch every project's pull
-	// requests just once.
-	projectsToPullRequests := make(map[string][]preport.PullRequest)
-	for _, n := range notConf.Notifiers {
+// checkConfig checks that the providers and notifiers every entry refers to are
+// configured, so that a missing credential fails the run rather than reporting
+// nothing.
+func (a *app) checkConfig(c notifierConfig) error {
+	for _, n := range c.Notifiers {
+		if _, ok := a.notifiers[n.notifierName()]; !ok {
+			return fmt.Errorf("%s: no notifier %q configured", n.displayChannel(), n.notifierName())
+		}
 		for _, p := range n.Projects {
-			if _, ok := projectsToPullRequests[p]; ok {
-				continue
+			provider, _ := parseProject(p)
+			if _, ok := a.listers[provider]; !ok {
+				return fmt.Errorf("%s: no provider %q configured", p, provider)
 			}
-			prs, err := gc.ListPullRequests(ctx, p, vcs.GitlabOptions{
-				Scope:           vcs.ScopeAll,
-				State:           vcs.StateOpened,
-				IsDraft:         &vcs.False,
-				HasAssignee:     &vcs.False,
-				HasBeenApproved: &vcs.False,
-				HasReviewer:     &vcs.False,
-				Sort:            vcs.SortAsc,
-			})
-			if err != nil {
-				errLog.Printf("vcs: Gitlab.ListPullRequests: %s", err)
-				continue
+		}
+		for _, g := range n.Groups {
+			provider, _ := parseProject(g.Group)
+			l, ok := a.listers[provider]
+			if !ok {
+				return fmt.Errorf("%s: no provider %q configured", g.Group, provider)
+			}
+			if _, ok := l.(preport.GroupLister); !ok {
+				return fmt.Errorf("%s: provider %q does not support groups", g.Group, provider)
 			}
-			projectsToPullRequests[p] = prs
 		}
 	}
+	return nil
+}
 
-	// Now send out a formatted Slack message to each channel, utilizing the
-	// projects we fetched earlier.
+// newApp returns an app using the providers and notifiers configured in
+// genConf. In dry-run mode, reports are written to stdout instead of being
+// delivered.
+func newApp(genConf generalConfig, stdout, stderr io.Writer) (*app, error) {
+	a := app{
+		listers:   make(map[string]preport.PullRequestLister),
+		notifiers: make(map[string]preport.Notifier),
+		errLog:    log.New(stderr, "", log.LstdFlags|log.Lshortfile),
+		chatIDs:   make(map[string]string),
+
+		suggestOwners: genConf.SuggestOwners,
+		dryRun:        genConf.DryRun,
+		assignState:   genConf.AssignStateFile,
+		templateData:  genConf.TemplateData,
+	}
+	if genConf.ChatIDs.File != "" {
+		var ids map[string]string
+		if err := decodeFile(genConf.ChatIDs.File, &ids); err != nil {
+			return nil, fmt.Errorf("decodeFile: %s", err)
+		}
+		// Users are referred to like projects, e.g. "github:alice", and
+		// refer to GitLab without a provider prefix.
+		for ref, id := range ids {
+			a.chatIDs[userKey(parseProject(ref))] = id
+		}
+	}
+
+	// Providers and notifiers are optional, and only registered when
+	// configured.
+	if genConf.Gitlab.Bearer != "" {
+		gc, err := vcs.NewGitlab(genConf.Gitlab.BaseURL, genConf.Gitlab.Bearer)
+		if err != nil {
+			return nil, fmt.Errorf("vcs: NewGitlab: %s", err)
+		}
+		gc.MaxPages = genConf.Gitlab.MaxPages
+		gc.WithApprovals = genConf.Gitlab.WithApprovals
+		gc.WithWaitingSince = genConf.Gitlab.WithWaitingSince
+		a.listers[providerGitlab] = gc
+	}
+	if genConf.GitHub.Bearer != "" {
+		ghc, err := vcs.NewGitHub(genConf.GitHub.BaseURL, genConf.GitHub.Bearer)
+		if err != nil {
+			return nil, fmt.Errorf("vcs: NewGitHub: %s", err)
+		}
+		ghc.MaxPages = genConf.GitHub.MaxPages
+		ghc.WithApprovals = genConf.GitHub.WithApprovals
+		a.listers[providerGitHub] = ghc
+	}
+	if genConf.Slack.Bearer != "" {
+		sc, err := notifier.NewSlack(genConf.Slack.BaseURL, genConf.Slack.Bearer)
+		if err != nil {
+			return nil, fmt.Errorf("notifier: NewSlack: %s", err)
+		}
+		sc.ThreadReplies = genConf.Slack.ThreadReplies
+		sc.MaxBlocksSize = genConf.Slack.MaxBlocksSize
+		a.notifiers[notifierSlack] = sc
+		if genConf.ChatIDs.LookupByEmail {
+			a.userLookup = sc
+		}
+	}
+	if genConf.ChatIDs.LookupByEmail && a.userLookup == nil {
+		return nil, errors.New("looking up chat IDs by email requires Slack to be configured")
+	}
+	if genConf.SMTP.Addr != "" {
+		e, err := notifier.NewEmail(genConf.SMTP.Addr, genConf.SMTP.From, genConf.SMTP.Username, genConf.SMTP.Password)
+		if err != nil {
+			return nil, fmt.Errorf("notifier: NewEmail: %s", err)
+		}
+		e.Subject = genConf.SMTP.Subject
+		e.RequireTLS = genConf.SMTP.RequireTLS
+		a.notifiers[notifierEmail] = e
+	}
+	// Teams and webhook URLs are used as channels, and need no configuration.
+	// Webhooks of entries with headers or a secret use a notifier of their
+	// own, see notifierConfig.validate.
+	a.notifiers[notifierTeams] = notifier.NewTeams()
+	wh, err := notifier.NewWebhook(nil, "")
+	if err != nil {
+		return nil, fmt.Errorf("notifier: NewWebhook: %s", err)
+	}
+	a.notifiers[notifierWebhook] = wh
+
+	if genConf.DryRun {
+		for name, n := range a.notifiers {
+			a.notifiers[name] = dryRunNotifier{name: name, n: n, w: stdout}
+		}
+	}
+	return &a, nil
+}
+
+// defaultFilter reports pull requests pending review, i.e. those that are not
+// drafts and have no assignee, approval or reviewer yet.
+var defaultFilter = preport.Filter{
+	IsDraft:         &vcs.False,
+	HasAssignee:     &vcs.False,
+	HasBeenApproved: &vcs.False,
+	HasReviewer:     &vcs.False,
+}
+
+// notifierName returns the name of the notifier of the entry, which defaults to
+// Slack.
+func (n notifierEntry) notifierName() string {
+	if n.Notifier == "" {
+		return notifierSlack
+	}
+	return n.Notifier
+}
+
+// displayChannel returns the channel of the entry for use in logs and errors.
+// The URLs of Teams incoming webhooks are credentials themselves, and those of
+// webhooks often carry tokens in their path or query, so only their scheme and
+// host are shown.
+func (n notifierEntry) displayChannel() string {
+	switch n.notifierName() {
+	case notifierTeams, notifierWebhook:
+		return redactURL(n.Channel)
+	}
+	return n.Channel
+}
+
+// redactURL returns the scheme and host of the URL s, leaving out its user
+// info, path and query.
+func redactURL(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" {
+		return "<invalid URL>"
+	}
+	return u.Scheme + "://" + u.Host + "/..."
+}
+
+func (n notifierEntry) templateData(def string) string {
+	if n.TemplateData == "" {
+		return def
+	}
+	return n.TemplateData
+}
+
+func (n notifierEntry) filter() preport.Filter {
+	if n.Filter == nil {
+		return defaultFilter
+	}
+	return *n.Filter
+}
+
+// fetchKey identifies a single fetch of pull requests, so that projects and
+// groups listed by many entries using the same filter are fetched just once.
+type fetchKey struct {
+	provider, id, filter string
+	group                bool
+}
+
+func newFetchKey(ref string, group bool, f preport.Filter) fetchKey {
+	provider, id := parseProject(ref)
+	// Marshaling a Filter cannot fail, and yields a comparable representation
+	// of the values its pointers point to.
+	b, _ := json.Marshal(f)
+	return fetchKey{provider: provider, id: id, filter: string(b), group: group}
+}
+
+func (a *app) report(ctx context.Context, notConf notifierConfig) {
+	// First, create a flat map of projects, groups and filters and fetch every
+	// combination's pull requests just once.
+	fetched := make(map[fetchKey][]preport.PullRequest)
+	errs := make(map[fetchKey]error)
+	fetch := func(ref string, key fetchKey, filter preport.Filter) {
+		// Failed fetches are not retried for every entry listing them, as
+		// each attempt may take as long as the timeout.
+		if _, ok := errs[key]; ok {
+			return
+		}
+		if err := a.fetch(ctx, fetched, ref, key, filter); err != nil {
+			a.errLog.Printf("Unable to fetch %s: %s", ref, err)
+			errs[key] = err
+		}
+	}
 	for _, n := range notConf.Notifiers {
-		prs := make([]preport.PullRequest, 0, len(n.Projects))
+		filter := n.filter()
 		for _, p := range n.Projects {
-			pr, ok := projectsToPullRequests[p]
+			fetch(p, newFetchKey(p, false, filter), filter)
+		}
+		for _, g := range n.Groups {
+			fetch(g.Group, newFetchKey(g.Group, true, filter), filter)
+		}
+	}
+
+	// Assign reviewers before reporting, so that every report listing a pull
+	// request includes its assigned reviewer.
+	as := newAssignment()
+	if a.assignState != "" {
+		if err := as.readState(a.assignState); err != nil {
+			a.errLog.Printf("Unable to read assignment state: %s", err)
+		}
+	}
+	for _, n := range notConf.Notifiers {
+		if n.Assign != nil {
+			a.assignReviewers(ctx, fetched, n, as)
+		}
+	}
+	if a.assignState != "" && !a.dryRun {
+		if err := as.writeState(a.assignState); err != nil {
+			a.errLog.Printf("Unable to write assignment state: %s", err)
+		}
+	}
+
+	// Now send out a formatted report to each channel, utilizing the projects
+	// and groups we fetched earlier.
+	for _, n := range notConf.Notifiers {
+		name := n.notifierName()
+		nt, ok := a.notifiers[name]
+		if !ok {
+			a.errLog.Printf("No notifier %q configured for %s; skipping", name, n.displayChannel())
+			continue
+		}
+		if n.webhook != nil {
+			nt = replaceNotifier(nt, n.webhook)
+		}
+		if n.Blocks {
+			sc, ok := unwrapNotifier(nt).(*notifier.Slack)
 			if !ok {
-				errLog.Printf("Missing PullRequest entry for %s; skipping", p)
+				a.errLog.Printf("Notifier %q does not support blocks for %s; skipping", name, n.displayChannel())
+				continue
+			}
+			nt = replaceNotifier(nt, sc.WithBlockKit())
+		}
+
+		r := a.collect(fetched, errs, n)
+		r.Notifier = name
+
+		// An empty report is only trusted when none of the projects failed,
+		// as it would otherwise falsely signal that all clear.
+		tmpl := n.tmpl
+		if r.Total == 0 && r.Complete {
+			if n.SkipEmpty {
 				continue
 			}
-			prs = append(prs, pr...)
+			if n.emptyTmpl != nil {
+				tmpl = n.emptyTmpl
+			}
 		}
 
-		text, err := renderTemplate(tmpl, prs)
+		text, err := r.render(tmpl, n.Sort, n.templateData(a.templateData))
 		if err != nil {
-			errLog.Printf("renderTemplate: %s", err)
+			a.errLog.Printf("Unable to render report for %s: %s", n.displayChannel(), err)
 			continue
 		}
-		if err := sc.Notify(ctx, n.Channel, text); err != nil {
-			errLog.Printf("notifier: Slack.Notify: %s", err)
+		if err := nt.Notify(ctx, n.Channel, text); err != nil {
+			a.errLog.Printf("%T: Notify: %s", nt, err)
 			continue
 		}
 	}
+}
+
+// fetch lists the pull requests of the project or group referred to by ref
+// into fetched, unless they were fetched before.
+func (a *app) fetch(ctx context.Context, fetched map[fetchKey][]preport.PullRequest, ref string, key fetchKey, f preport.Filter) error {
+	if _, ok := fetched[key]; ok {
+		return nil
+	}
+
+	l, ok := a.listers[key.provider]
+	if !ok {
+		return fmt.Errorf("no provider %q configured", key.provider)
+	}
+	var prs []preport.PullRequest
+	if !key.group {
+		var err error
+		if prs, err = l.ListPullRequests(ctx, key.id, f); err != nil {
+			return fmt.Errorf("%T: ListPullRequests: %s", l, err)
+		}
+	} else {
+		gl, ok := l.(preport.GroupLister)
+		if !ok {
+			return fmt.Errorf("provider %q does not support groups", key.provider)
+		}
+		var err error
+		if prs, err = gl.ListGroupPullRequests(ctx, key.id, f); err != nil {
+			return fmt.Errorf("%T: ListGroupPullRequests: %s", gl, err)
+		}
+	}
+
+	sug, _ := l.(preport.OwnerSuggester)
+	for i := range prs {
+		prs[i].Project.Provider = key.provider
+		if a.suggestOwners && sug != nil {
+			// Without suggestions the pull request is still worth reporting.
+			owners, err := sug.SuggestOwners(ctx, prs[i])
+			if err != nil {
+				a.errLog.Printf("%T: SuggestOwners: %s", sug, err)
+			}
+			prs[i].SuggestedOwners = owners
+		}
+		a.resolveChatIDs(ctx, key.provider, l, &prs[i])
+	}
+	fetched[key] = prs
 	return nil
 }
 
-func renderTemplate(tmpl *template.Template, prs []preport.PullRequest) (string, error) {
-	sort.Sort(preport.PullRequestsByCreatedAt(prs))
+// resolveChatIDs sets the chat member ID of every user involved in pr, which
+// is listed by l of provider.
+func (a *app) resolveChatIDs(ctx context.Context, provider string, l preport.PullRequestLister, pr *preport.PullRequest) {
+	authors := []*preport.Author{&pr.Author}
+	for _, as := range [][]preport.Author{pr.Assignees, pr.Reviewers, pr.Approval.ApprovedBy, pr.SuggestedOwners} {
+		for i := range as {
+			authors = append(authors, &as[i])
+		}
+	}
+	for _, au := range authors {
+		au.ChatID = a.chatID(ctx, provider, l, au.Username)
+	}
+}
+
+// chatID returns the chat member ID of the user of provider with username, or
+// an empty string when unknown.
+func (a *app) chatID(ctx context.Context, provider string, l preport.PullRequestLister, username string) string {
+	key := userKey(provider, username)
+	if id, ok := a.chatIDs[key]; ok {
+		return id
+	}
+	el, ok := l.(preport.EmailLookup)
+	if a.userLookup == nil || !ok {
+		return ""
+	}
+
+	// Remember users that could not be looked up as well, so that every user
+	// is looked up at most once.
+	a.chatIDs[key] = ""
+	email, err := el.LookupEmail(ctx, username)
+	if err != nil {
+		a.errLog.Printf("%T: LookupEmail: %s", el, err)
+		return ""
+	}
+	if email == "" {
+		return ""
+	}
+	id, err := a.userLookup.LookupUserByEmail(ctx, email)
+	if err != nil {
+		a.errLog.Printf("%T: LookupUserByEmail: %s", a.userLookup, err)
+		return ""
+	}
+	a.chatIDs[key] = id
+	return id
+}
+
+const (
+	providerGitlab = "gitlab"
+	providerGitHub = "github"
+
+	notifierSlack   = "slack"
+	notifierEmail   = "email"
+	notifierTeams   = "teams"
+	notifierWebhook = "webhook"
+
+	sortCreated = "created"
+	sortUpdated = "updated"
+	sortWaiting = "waiting"
+)
+
+// parseProject splits a project reference formatted as "provider:id" into its
+// provider and ID. References without a provider prefix refer to GitLab.
+func parseProject(ref string) (provider, id string) {
+	if i := strings.Index(ref, ":"); i != -1 {
+		return ref[:i], ref[i+1:]
+	}
+	return providerGitlab, ref
+}
+
+// userKey identifies the user with username across providers.
+func userKey(provider, username string) string {
+	return provider + ":" + username
+}
+
+// newTemplate parses text as a report template, providing funcs.
+func newTemplate(name, text string, funcs template.FuncMap) (*template.Template, error) {
+	return template.New(name).Funcs(funcs).Parse(text)
+}
 
+// renderTemplate renders tmpl with data, which is either a list of pull
+// requests or a report.
+func renderTemplate(tmpl *template.Template, data interface{}) (string, error) {
 	var b strings.Builder
-	if err := tmpl.Execute(&b, prs); err != nil {
+	if err := tmpl.Execute(&b, data); err != nil {
 		return "", fmt.Errorf("text/template: Template.Execute: %s", err)
 	}
 	return b.String(), nil
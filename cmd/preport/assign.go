@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/epels/preport"
+)
+
+type assignEntry struct {
+	// Pool lists the usernames of the reviewers to choose from.
+	Pool []string
+	// Strategy is either strategyRoundRobin, the default, or
+	// strategyLeastLoaded.
+	Strategy string
+}
+
+const (
+	// strategyRoundRobin rotates through the pool, picking the reviewer
+	// following the one picked last for every pull request.
+	strategyRoundRobin = "round-robin"
+	// strategyLeastLoaded picks the reviewer with the fewest open reviews,
+	// preferring those listed first in the pool on a tie.
+	strategyLeastLoaded = "least-loaded"
+)
+
+// assignment keeps track of the reviewers assigned during a single report, so
+// that pull requests listed by many entries are assigned a reviewer just once.
+type assignment struct {
+	// assigned maps the URLs of pull requests to their assigned reviewer.
+	assigned map[string]preport.Author
+	// loads maps users, keyed by userKey, to their number of open reviews.
+	loads map[string]int
+	// last maps pools, keyed by poolKey, to the reviewer picked last from
+	// them by round-robin.
+	last map[string]string
+}
+
+func newAssignment() *assignment {
+	return &assignment{
+		assigned: make(map[string]preport.Author),
+		loads:    make(map[string]int),
+		last:     make(map[string]string),
+	}
+}
+
+// poolKey identifies pool, so that entries sharing a pool share its rotation.
+func poolKey(pool []string) string {
+	return strings.Join(pool, ",")
+}
+
+// readState reads the reviewers picked last by round-robin from the JSON file
+// at path, which may not exist yet.
+func (as *assignment) readState(path string) error {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+	if err := decodeFile(path, &as.last); err != nil {
+		return fmt.Errorf("decodeFile: %s", err)
+	}
+	return nil
+}
+
+// writeState writes the reviewers picked last by round-robin to the JSON file
+// at path.
+func (as *assignment) writeState(path string) error {
+	b, err := json.MarshalIndent(as.last, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding/json: MarshalIndent: %s", err)
+	}
+	if err := ioutil.WriteFile(path, b, 0o644); err != nil {
+		return fmt.Errorf("io/ioutil: WriteFile: %s", err)
+	}
+	return nil
+}
+
+// assignReviewers assigns a reviewer from the pool of n to each of its fetched
+// pull requests without one, recording the choice on the pull request. In
+// dry-run mode, reviewers are chosen but not actually assigned.
+func (a *app) assignReviewers(ctx context.Context, fetched map[fetchKey][]preport.PullRequest, n notifierEntry, as *assignment) {
+	switch n.Assign.Strategy {
+	case "", strategyRoundRobin, strategyLeastLoaded:
+	default:
+		a.errLog.Printf("Unknown strategy %q for %s; skipping assignment", n.Assign.Strategy, n.displayChannel())
+		return
+	}
+
+	// Group exclusions apply to assignment as they do to reporting.
+	type source struct {
+		key   fetchKey
+		group groupEntry
+	}
+	filter := n.filter()
+	var sources []source
+	for _, p := range n.Projects {
+		sources = append(sources, source{key: newFetchKey(p, false, filter)})
+	}
+	for _, g := range n.Groups {
+		sources = append(sources, source{key: newFetchKey(g.Group, true, filter), group: g})
+	}
+
+	for _, src := range sources {
+		l, ok := a.listers[src.key.provider]
+		if !ok {
+			continue
+		}
+		ra, ok := l.(preport.ReviewerAssigner)
+		if !ok {
+			a.errLog.Printf("Provider %q does not support assigning reviewers; skipping", src.key.provider)
+			continue
+		}
+
+		prs := fetched[src.key]
+		for i := range prs {
+			pr := &prs[i]
+			if src.group.excludes(pr.Project.Path) {
+				continue
+			}
+			if r, ok := as.assigned[pr.URL]; ok && pr.URL != "" {
+				pr.AssignedReviewer = &r
+				continue
+			}
+			if len(pr.Reviewers) != 0 || len(pr.ReviewerTeams) != 0 {
+				continue
+			}
+
+			username := a.chooseReviewer(ctx, ra, src.key.provider, n.Assign, *pr, as)
+			if username == "" {
+				a.errLog.Printf("No reviewer available for %s", pr.URL)
+				continue
+			}
+			if !a.dryRun {
+				if err := ra.AssignReviewer(ctx, *pr, username); err != nil {
+					a.errLog.Printf("%T: AssignReviewer: %s", ra, err)
+					continue
+				}
+			}
+
+			r := preport.Author{Username: username, ChatID: a.chatID(ctx, src.key.provider, l, username)}
+			pr.AssignedReviewer = &r
+			as.assigned[pr.URL] = r
+			as.loads[userKey(src.key.provider, username)]++
+		}
+	}
+}
+
+// chooseReviewer chooses a reviewer for pr from the pool according to the
+// strategy, excluding its author. It returns an empty string when no one is
+// available.
+func (a *app) chooseReviewer(ctx context.Context, ra preport.ReviewerAssigner, provider string, ae *assignEntry, pr preport.PullRequest, as *assignment) string {
+	var candidates []string
+	for _, u := range ae.Pool {
+		if u != pr.Author.Username {
+			candidates = append(candidates, u)
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	if ae.Strategy != strategyLeastLoaded {
+		return as.next(ae.Pool, pr.Author.Username)
+	}
+
+	var chosen string
+	minLoad := -1
+	for _, u := range candidates {
+		key := userKey(provider, u)
+		load, ok := as.loads[key]
+		if !ok {
+			var err error
+			if load, err = ra.CountReviews(ctx, u); err != nil {
+				a.errLog.Printf("%T: CountReviews: %s", ra, err)
+				continue
+			}
+			as.loads[key] = load
+		}
+		if minLoad == -1 || load < minLoad {
+			chosen, minLoad = u, load
+		}
+	}
+	return chosen
+}
+
+// next picks the reviewer following the one picked last from pool, skipping
+// author, and remembers the pick. It returns an empty string when no one but
+// the author is in the pool.
+func (as *assignment) next(pool []string, author string) string {
+	key := poolKey(pool)
+	start := 0
+	for i, u := range pool {
+		if u == as.last[key] {
+			start = i + 1
+			break
+		}
+	}
+	for i := 0; i < len(pool); i++ {
+		u := pool[(start+i)%len(pool)]
+		if u != author {
+			as.last[key] = u
+			return u
+		}
+	}
+	return ""
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/epels/preport"
+)
+
+// readHolidays reads the dates of all events in the iCalendar file at path, as
+// exported by most calendar applications. Events spanning multiple days yield
+// each of their dates. Recurrence rules are not supported, so recurring
+// holidays must be listed for every year.
+func readHolidays(path string) ([]time.Time, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("os: Open: %s", err)
+	}
+	defer f.Close()
+
+	// Long lines are folded by continuing them on lines starting with a space
+	// or tab, so they are unfolded before being parsed.
+	var lines []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line := strings.TrimRight(sc.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) != 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("bufio: Scanner.Scan: %s", err)
+	}
+
+	var (
+		holidays   []time.Time
+		inEvent    bool
+		start, end time.Time
+	)
+	for i, line := range lines {
+		name, value := line, ""
+		if j := strings.Index(line, ":"); j != -1 {
+			name, value = line[:j], line[j+1:]
+		}
+		// Parameters such as VALUE=DATE or TZID do not affect the date.
+		if j := strings.Index(name, ";"); j != -1 {
+			name = name[:j]
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			inEvent, start, end = true, time.Time{}, time.Time{}
+		case name == "END" && value == "VEVENT":
+			if start.IsZero() {
+				return nil, fmt.Errorf("%s:%d: event without DTSTART", path, i+1)
+			}
+			holidays = append(holidays, start)
+			// The end date of an event is exclusive.
+			for d := start.AddDate(0, 0, 1); d.Before(end); d = d.AddDate(0, 0, 1) {
+				holidays = append(holidays, d)
+			}
+			inEvent = false
+		case inEvent && (name == "DTSTART" || name == "DTEND"):
+			if len(value) < len("20060102") {
+				return nil, fmt.Errorf("%s:%d: invalid date: %q", path, i+1, value)
+			}
+			d, err := time.Parse("20060102", value[:len("20060102")])
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: time: Parse: %s", path, i+1, err)
+			}
+			if name == "DTSTART" {
+				start = d
+			} else {
+				end = d
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// businessHoursConfig configures business hours, defaulting to 09:00 to 17:00
+// UTC on weekdays for every empty field, so that its zero value is usable.
+type businessHoursConfig struct {
+	Timezone string
+	Weekdays []string
+	// Start and End are formatted as "15:04".
+	Start string
+	End   string
+	// Holidays is the path to an iCalendar file listing holidays.
+	Holidays string
+}
+
+// parse validates the configuration, and reads the holidays file if set.
+func (c businessHoursConfig) parse() (preport.BusinessHours, error) {
+	if c.Timezone == "" {
+		c.Timezone = "UTC"
+	}
+	if len(c.Weekdays) == 0 {
+		c.Weekdays = []string{"monday", "tuesday", "wednesday", "thursday", "friday"}
+	}
+	if c.Start == "" {
+		c.Start = "09:00"
+	}
+	if c.End == "" {
+		c.End = "17:00"
+	}
+
+	loc, err := time.LoadLocation(c.Timezone)
+	if err != nil {
+		return preport.BusinessHours{}, fmt.Errorf("time: LoadLocation: %s", err)
+	}
+	bh := preport.BusinessHours{Location: loc}
+
+	for _, wd := range c.Weekdays {
+		d, ok := weekdays[strings.ToLower(strings.TrimSpace(wd))]
+		if !ok {
+			return preport.BusinessHours{}, fmt.Errorf("unknown weekday: %q", wd)
+		}
+		bh.Weekdays = append(bh.Weekdays, d)
+	}
+	if bh.Start, err = parseClock(c.Start); err != nil {
+		return preport.BusinessHours{}, err
+	}
+	if bh.End, err = parseClock(c.End); err != nil {
+		return preport.BusinessHours{}, err
+	}
+	if bh.End <= bh.Start {
+		return preport.BusinessHours{}, fmt.Errorf("end %s must be after start %s", c.End, c.Start)
+	}
+
+	if c.Holidays != "" {
+		if bh.Holidays, err = readHolidays(c.Holidays); err != nil {
+			return preport.BusinessHours{}, fmt.Errorf("readHolidays: %s", err)
+		}
+	}
+	return bh, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// parseClock parses a time of day formatted as "15:04" into its offset from
+// midnight.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time: Parse: %s", err)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBusinessHoursConfig_parse(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		bh, err := businessHoursConfig{
+			Timezone: "Europe/Amsterdam",
+			Weekdays: []string{"Monday", "tuesday"},
+			Start:    "08:30",
+			End:      "16:00",
+			Holidays: "testdata/holidays.ics",
+		}.parse()
+		require.NoError(t, err)
+		assert.Equal(t, "Europe/Amsterdam", bh.Location.String())
+		assert.Equal(t, []time.Weekday{time.Monday, time.Tuesday}, bh.Weekdays)
+		assert.Equal(t, 8*time.Hour+30*time.Minute, bh.Start)
+		assert.Equal(t, 16*time.Hour, bh.End)
+		assert.Equal(t, []time.Time{
+			time.Date(2021, 12, 25, 0, 0, 0, 0, time.UTC),
+			time.Date(2021, 12, 26, 0, 0, 0, 0, time.UTC),
+			time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		}, bh.Holidays)
+	})
+
+	t.Run("Defaults", func(t *testing.T) {
+		bh, err := businessHoursConfig{}.parse()
+		require.NoError(t, err)
+		assert.Equal(t, time.UTC, bh.Location)
+		assert.Equal(t, []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}, bh.Weekdays)
+		assert.Equal(t, 9*time.Hour, bh.Start)
+		assert.Equal(t, 17*time.Hour, bh.End)
+		assert.Empty(t, bh.Holidays)
+	})
+
+	for _, tc := range []struct {
+		name string
+		c    businessHoursConfig
+	}{
+		{name: "Unknown timezone", c: businessHoursConfig{Timezone: "Mars/Olympus"}},
+		{name: "Unknown weekday", c: businessHoursConfig{Weekdays: []string{"caturday"}}},
+		{name: "Invalid start", c: businessHoursConfig{Start: "9am"}},
+		{name: "End before start", c: businessHoursConfig{Start: "17:00", End: "09:00"}},
+		{name: "End before default start", c: businessHoursConfig{End: "08:00"}},
+		{name: "Missing holidays", c: businessHoursConfig{Holidays: "testdata/missing.ics"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := tc.c.parse()
+			require.Error(t, err)
+		})
+	}
+}
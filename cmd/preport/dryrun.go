@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/epels/preport"
+)
+
+// payloader is implemented by notifiers that can expose the exact payload they
+// would send for a report.
+type payloader interface {
+	Payload(destination, content string) ([]byte, error)
+}
+
+// dryRunNotifier writes reports to w instead of delivering them through the
+// notifier it wraps.
+type dryRunNotifier struct {
+	name string
+	n    preport.Notifier
+	w    io.Writer
+}
+
+// unwrapNotifier returns the notifier nt delivers through, which is wrapped in
+// dry-run mode.
+func unwrapNotifier(nt preport.Notifier) preport.Notifier {
+	if d, ok := nt.(dryRunNotifier); ok {
+		return d.n
+	}
+	return nt
+}
+
+// replaceNotifier returns nt delivering through n instead, keeping it wrapped
+// in dry-run mode.
+func replaceNotifier(nt, n preport.Notifier) preport.Notifier {
+	if d, ok := nt.(dryRunNotifier); ok {
+		d.n = n
+		return d
+	}
+	return n
+}
+
+func (d dryRunNotifier) Notify(_ context.Context, destination, report string) error {
+	if _, err := fmt.Fprintf(d.w, "=== %s: %s\n%s\n", d.name, destination, report); err != nil {
+		return fmt.Errorf("fmt: Fprintf: %s", err)
+	}
+
+	p, ok := d.n.(payloader)
+	if !ok {
+		return nil
+	}
+	b, err := p.Payload(destination, report)
+	if err != nil {
+		return fmt.Errorf("%T: Payload: %s", d.n, err)
+	}
+	if _, err := fmt.Fprintf(d.w, "--- payload\n%s\n", b); err != nil {
+		return fmt.Errorf("fmt: Fprintf: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// decodeFile decodes the JSON or YAML file at path into v, depending on its
+// extension. YAML is converted to JSON first, so that both formats use the
+// same field names. Errors point to the location in the file where possible.
+func decodeFile(path string, v interface{}) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("io/ioutil: ReadFile: %s", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var yv interface{}
+		if err := yaml.Unmarshal(b, &yv); err != nil {
+			return fmt.Errorf("%s: gopkg.in/yaml.v3: Unmarshal: %s", path, err)
+		}
+		if b, err = json.Marshal(yv); err != nil {
+			return fmt.Errorf("%s: encoding/json: Marshal: %s", path, err)
+		}
+	}
+
+	if err := json.Unmarshal(b, v); err != nil {
+		var se *json.SyntaxError
+		if errors.As(err, &se) {
+			line, col := position(b, se.Offset)
+			return fmt.Errorf("%s:%d:%d: encoding/json: Unmarshal: %s", path, line, col, err)
+		}
+		return fmt.Errorf("%s: encoding/json: Unmarshal: %s", path, err)
+	}
+	return nil
+}
+
+// position returns the line and column of offset in b, both starting at 1.
+func position(b []byte, offset int64) (line, col int) {
+	line, col = 1, 1
+	for i := int64(0); i < offset-1 && i < int64(len(b)); i++ {
+		if b[i] == '\n' {
+			line++
+			col = 1
+			continue
+		}
+		col++
+	}
+	return line, col
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/epels/preport"
+	"github.com/epels/preport/notifier"
+)
+
+// templateFuncs returns the functions available to all report templates, with
+// ages measured up to now in the working time of bh. Dates are formatted in the
+// time zone of bh.
+func templateFuncs(bh preport.BusinessHours, now time.Time) template.FuncMap {
+	loc := bh.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+	businessAge := func(t time.Time) time.Duration {
+		return bh.Between(t, now).Round(time.Second)
+	}
+
+	return template.FuncMap{
+		"json":        toJSON,
+		"now":         func() time.Time { return now },
+		"businessAge": businessAge,
+		// businessHours is meant for thresholds, as durations are awkward to
+		// compare in templates, e.g. {{if ge (businessHours .CreatedAt) 8.0}}.
+		"businessHours": func(t time.Time) float64 {
+			return businessAge(t).Hours()
+		},
+		"date": func(layout string, t time.Time) string {
+			return t.In(loc).Format(layout)
+		},
+
+		"humanize":    humanize,
+		"hours":       func(d time.Duration) float64 { return d.Hours() },
+		"days":        func(d time.Duration) float64 { return d.Hours() / 24 },
+		"pluralize":   pluralize,
+		"truncate":    truncate,
+		"slackEscape": slackEscape,
+		"slackBlocks": slackBlocks,
+		"slackBoundary": func() string {
+			return notifier.SlackBoundary
+		},
+		"mention": mention,
+		"groupBy": groupBy,
+		"sortBy":  sortBy,
+		"reverse": reverse,
+
+		"add": func(a, b int) int { return a + b },
+		"sub": func(a, b int) int { return a - b },
+		"mul": func(a, b int) int { return a * b },
+		"div": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return a / b, nil
+		},
+		"mod": func(a, b int) (int, error) {
+			if b == 0 {
+				return 0, fmt.Errorf("division by zero")
+			}
+			return a % b, nil
+		},
+		"round": func(f float64) int { return int(math.Round(f)) },
+	}
+}
+
+// toJSON encodes v as JSON, e.g. to safely embed strings in webhook bodies.
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encoding/json: Marshal: %s", err)
+	}
+	return string(b), nil
+}
+
+// humanize formats d in its largest whole unit, e.g. "3 days" or "1 hour".
+func humanize(d time.Duration) string {
+	switch {
+	case d >= 24*time.Hour:
+		n := int(d / (24 * time.Hour))
+		return fmt.Sprintf("%d %s", n, pluralize(n, "day", "days"))
+	case d >= time.Hour:
+		n := int(d / time.Hour)
+		return fmt.Sprintf("%d %s", n, pluralize(n, "hour", "hours"))
+	case d >= time.Minute:
+		n := int(d / time.Minute)
+		return fmt.Sprintf("%d %s", n, pluralize(n, "minute", "minutes"))
+	default:
+		return "just now"
+	}
+}
+
+// pluralize returns singular when n is one, and plural otherwise.
+func pluralize(n int, singular, plural string) string {
+	if n == 1 {
+		return singular
+	}
+	return plural
+}
+
+// truncate shortens s to at most n characters, ending it with an ellipsis when
+// anything was cut off.
+func truncate(n int, s string) string {
+	if n <= 0 {
+		return ""
+	}
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+// slackEscape escapes the characters with a special meaning in Slack's mrkdwn,
+// so that e.g. titles cannot break links or mention everyone.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// mention formats a mention of a in Slack, falling back to their username when
+// their chat ID is unknown.
+func mention(a preport.Author) string {
+	if a.ChatID != "" {
+		return "<@" + a.ChatID + ">"
+	}
+	return slackEscape(a.Username)
+}
+
+// slackBlocks renders prs in the built-in Block Kit layout for entries posting
+// blocks to Slack: a section with a button linking to every pull request,
+// followed by its age and reviewers, separated by dividers.
+func slackBlocks(prs []preport.PullRequest) (string, error) {
+	blocks := []notifier.SlackBlock{}
+	for i, pr := range prs {
+		if i > 0 {
+			blocks = append(blocks, notifier.SlackBlock{Type: "divider"})
+		}
+
+		title := fmt.Sprintf("*<%s|%s>*", pr.URL, slackEscape(pr.Title))
+		if pr.URL == "" {
+			title = "*" + slackEscape(pr.Title) + "*"
+		}
+		blocks = append(blocks, notifier.SlackBlock{
+			Type: "section",
+			Text: &notifier.SlackText{
+				Type: "mrkdwn",
+				Text: fmt.Sprintf("%s\n`%s` by %s", title, slackEscape(pr.Project.Path), mention(pr.Author)),
+			},
+		})
+		if pr.URL != "" {
+			blocks[len(blocks)-1].Accessory = &notifier.SlackButton{
+				Type: "button",
+				Text: notifier.SlackText{Type: "plain_text", Text: "Review"},
+				URL:  pr.URL,
+			}
+		}
+
+		details := []string{"Open for " + humanize(pr.TimeOpen())}
+		if !pr.WaitingSince.IsZero() {
+			details = append(details, "waiting for "+humanize(pr.TimeWaiting()))
+		}
+		if pr.AssignedReviewer != nil {
+			details = append(details, "assigned to "+mention(*pr.AssignedReviewer))
+		}
+		if len(pr.SuggestedOwners) != 0 {
+			var owners []string
+			for _, o := range pr.SuggestedOwners {
+				owners = append(owners, mention(o))
+			}
+			details = append(details, "owned by "+strings.Join(owners, ", "))
+		}
+		blocks = append(blocks, notifier.SlackBlock{
+			Type:     "context",
+			Elements: []notifier.SlackText{{Type: "mrkdwn", Text: strings.Join(details, " · ")}},
+		})
+	}
+	return toJSON(blocks)
+}
+
+// group is a set of pull requests sharing the same value for a field.
+type group struct {
+	Key          string
+	PullRequests []preport.PullRequest
+}
+
+// groupBy groups prs by the value of field, e.g. "Project.Path" or
+// "Author.Username", in order of first appearance.
+func groupBy(field string, prs []preport.PullRequest) ([]group, error) {
+	var groups []group
+	index := make(map[string]int)
+	for _, pr := range prs {
+		v, err := fieldValue(pr, field)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprint(v.Interface())
+
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, group{Key: key})
+		}
+		groups[i].PullRequests = append(groups[i].PullRequests, pr)
+	}
+	return groups, nil
+}
+
+// sortBy returns a copy of prs sorted in ascending order by the value of field,
+// which may also be a method such as "TimeOpen". Pull requests with equal
+// values keep their order.
+func sortBy(field string, prs []preport.PullRequest) ([]preport.PullRequest, error) {
+	values := make([]reflect.Value, len(prs))
+	idx := make([]int, len(prs))
+	for i, pr := range prs {
+		v, err := fieldValue(pr, field)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = v
+		idx[i] = i
+	}
+
+	var err error
+	sort.SliceStable(idx, func(i, j int) bool {
+		less, lErr := lessValue(values[idx[i]], values[idx[j]])
+		if lErr != nil {
+			err = lErr
+		}
+		return less
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]preport.PullRequest, 0, len(prs))
+	for _, i := range idx {
+		sorted = append(sorted, prs[i])
+	}
+	return sorted, nil
+}
+
+// reverse returns a copy of prs in reverse order.
+func reverse(prs []preport.PullRequest) []preport.PullRequest {
+	reversed := make([]preport.PullRequest, 0, len(prs))
+	for i := len(prs) - 1; i >= 0; i-- {
+		reversed = append(reversed, prs[i])
+	}
+	return reversed
+}
+
+// fieldValue resolves a dot separated path of fields and methods without
+// arguments on pr, like templates do.
+func fieldValue(pr preport.PullRequest, path string) (reflect.Value, error) {
+	v := reflect.ValueOf(pr)
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v = reflect.Zero(v.Type().Elem())
+			} else {
+				v = v.Elem()
+			}
+		}
+
+		if m := v.MethodByName(name); m.IsValid() && m.Type().NumIn() == 0 && m.Type().NumOut() == 1 {
+			v = m.Call(nil)[0]
+			continue
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("can't evaluate field %s of %s", name, v.Type())
+		}
+		f := v.FieldByName(name)
+		if !f.IsValid() {
+			return reflect.Value{}, fmt.Errorf("can't evaluate field %s of %s", name, v.Type())
+		}
+		v = f
+	}
+	return v, nil
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// lessValue reports whether a sorts before b, for values of the same type.
+func lessValue(a, b reflect.Value) (bool, error) {
+	if a.Type() == timeType {
+		return a.Interface().(time.Time).Before(b.Interface().(time.Time)), nil
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() < b.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return a.Uint() < b.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return a.Float() < b.Float(), nil
+	case reflect.String:
+		return a.String() < b.String(), nil
+	case reflect.Bool:
+		return !a.Bool() && b.Bool(), nil
+	default:
+		return false, fmt.Errorf("can't sort by values of type %s", a.Type())
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport"
+	"github.com/epels/preport/internal/testutil"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	ams, err := time.LoadLocation("Europe/Amsterdam")
+	require.NoError(t, err)
+	bh := preport.BusinessHours{
+		Location: ams,
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Start:    9 * time.Hour,
+		End:      17 * time.Hour,
+	}
+	now := time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)
+	prs := []preport.PullRequest{
+		{Title: "a", Project: preport.Project{Path: "g/x"}, CreatedAt: time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC), AsOf: now},
+		{Title: "b", Project: preport.Project{Path: "g/y"}, CreatedAt: time.Date(2021, 1, 4, 11, 0, 0, 0, time.UTC), AsOf: now},
+		{Title: "c", Project: preport.Project{Path: "g/x"}, CreatedAt: time.Date(2021, 1, 3, 9, 0, 0, 0, time.UTC), AsOf: now},
+	}
+
+	for _, tc := range []struct {
+		name, text, want string
+	}{
+		{name: "now", text: `{{now.Format "2006-01-02"}}`, want: "2021-01-04"},
+		{name: "date", text: `{{date "15:04 MST" (index . 0).CreatedAt}}`, want: "10:00 CET"},
+		{name: "businessAge", text: `{{businessAge (index . 0).CreatedAt}}`, want: "11h0m0s"},
+		{name: "businessHours", text: `{{if ge (businessHours (index . 0).CreatedAt) 11.0}}late{{end}}`, want: "late"},
+		{name: "humanize", text: `{{range .}}{{humanize .TimeOpen}},{{end}}`, want: "3 days,1 hour,1 day,"},
+		{name: "hours", text: `{{hours (index . 1).TimeOpen}}`, want: "1"},
+		{name: "days", text: `{{round (days (index . 0).TimeOpen)}}`, want: "3"},
+		{name: "pluralize", text: `{{len .}} {{pluralize (len .) "MR" "MRs"}}`, want: "3 MRs"},
+		{name: "truncate", text: `{{truncate 6 "Add upload"}}`, want: "Add u…"},
+		{name: "slackEscape", text: `{{slackEscape "<!channel> & more"}}`, want: "&lt;!channel&gt; &amp; more"},
+		{
+			name: "groupBy",
+			text: `{{range groupBy "Project.Path" .}}{{.Key}}:{{range .PullRequests}}{{.Title}}{{end}} {{end}}`,
+			want: "g/x:ac g/y:b ",
+		},
+		{name: "sortBy", text: `{{range sortBy "TimeOpen" .}}{{.Title}}{{end}}`, want: "bca"},
+		{name: "sortBy field", text: `{{range sortBy "Project.Path" .}}{{.Title}}{{end}}`, want: "acb"},
+		{name: "reverse", text: `{{range reverse .}}{{.Title}}{{end}}`, want: "cba"},
+		{name: "math", text: `{{add 1 2}} {{sub 1 2}} {{mul 2 3}} {{div 7 2}} {{mod 7 2}}`, want: "3 -1 6 3 1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tmpl, err := newTemplate(tc.name, tc.text, templateFuncs(bh, now))
+			require.NoError(t, err)
+
+			var b strings.Builder
+			require.NoError(t, tmpl.Execute(&b, prs))
+			assert.Equal(t, tc.want, b.String())
+		})
+	}
+
+	for _, text := range []string{
+		`{{div 1 0}}`,
+		`{{mod 1 0}}`,
+		`{{groupBy "Missing" .}}`,
+		`{{sortBy "Labels" .}}`,
+	} {
+		tmpl := template.Must(newTemplate("", text, templateFuncs(bh, now)))
+		assert.Error(t, tmpl.Execute(&strings.Builder{}, prs), "Expected error executing %s", text)
+	}
+}
+
+func TestSlackBlocks(t *testing.T) {
+	now := time.Date(2021, 1, 4, 12, 0, 0, 0, time.UTC)
+	s, err := slackBlocks([]preport.PullRequest{
+		{
+			Title:            "Add <upload>",
+			URL:              "https://gitlab.com/group/repo/-/merge_requests/14",
+			Author:           preport.Author{Username: "alice", ChatID: "U0ALICE"},
+			Project:          preport.Project{Path: "group/repo"},
+			CreatedAt:        time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC),
+			WaitingSince:     time.Date(2021, 1, 4, 10, 0, 0, 0, time.UTC),
+			AssignedReviewer: &preport.Author{Username: "bob"},
+			SuggestedOwners:  []preport.Author{{Username: "carol", ChatID: "U0CAROL"}, {Username: "dave"}},
+			AsOf:             now,
+		},
+		{
+			Title:     "Fix typo",
+			Author:    preport.Author{Username: "bob"},
+			Project:   preport.Project{Path: "group/repo"},
+			CreatedAt: time.Date(2021, 1, 4, 11, 30, 0, 0, time.UTC),
+			AsOf:      now,
+		},
+	})
+	require.NoError(t, err)
+	testutil.AssertTestdataJSONEquals(t, "testdata/slack_blocks.json", strings.NewReader(s))
+
+	s, err = slackBlocks(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "[]", s)
+}
+
+func TestHumanize(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		30 * time.Second:             "just now",
+		time.Minute:                  "1 minute",
+		59 * time.Minute:             "59 minutes",
+		27*time.Hour + 3*time.Minute: "1 day",
+		64 * time.Hour:               "2 days",
+	} {
+		assert.Equal(t, want, humanize(d), "Unexpected result for %s", d)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/kelseyhightower/envconfig"
 )
@@ -13,17 +14,78 @@ import (
 type generalConfig struct {
 	NotifierConfig string `required:"true" split_words:"true"`
 	ReportTemplate string `required:"true" split_words:"true"`
-	Gitlab         struct {
-		BaseURL string `required:"true" split_words:"true"`
-		Bearer  string `required:"true" split_words:"true"`
-	} `required:"true" split_words:"true"`
+	// TemplateData is the data report templates are rendered with. It defaults
+	// to the list of pull requests, while "report" renders them with a report
+	// including its channel, time and failed projects.
+	TemplateData string `default:"pullrequests" split_words:"true"`
+	// DryRun writes the reports to stdout instead of delivering them.
+	DryRun bool `split_words:"true"`
+	// Gitlab and GitHub are optional, and only required when any of the
+	// notifiers lists projects hosted by them.
+	Gitlab struct {
+		BaseURL          string `default:"https://gitlab.com" split_words:"true"`
+		Bearer           string `split_words:"true"`
+		MaxPages         int    `default:"10" split_words:"true"`
+		WithApprovals    bool   `split_words:"true"`
+		WithWaitingSince bool   `split_words:"true"`
+	} `split_words:"true"`
+	GitHub struct {
+		BaseURL       string `default:"https://api.github.com" split_words:"true"`
+		Bearer        string `split_words:"true"`
+		MaxPages      int    `default:"10" split_words:"true"`
+		WithApprovals bool   `split_words:"true"`
+	} `envconfig:"github"`
+	// SuggestOwners suggests reviewers for every pull request based on the
+	// CODEOWNERS file of its project, for providers supporting it. This costs
+	// an additional request for each pull request.
+	SuggestOwners bool `split_words:"true"`
+	// AssignStateFile is the path of a JSON file in which round-robin
+	// assignment keeps its position in every pool, so that rotation carries
+	// over between runs. Without it, every run starts at the top of the pool.
+	AssignStateFile string `split_words:"true"`
+	// Slack is optional, and only required when any of the notifiers uses
+	// it. ThreadReplies posts the messages following the first one of a report
+	// too large for a single message in its thread, and MaxBlocksSize
+	// overrides the size in bytes reports are split up at.
 	Slack struct {
-		BaseURL string `required:"true" split_words:"true"`
-		Bearer  string `required:"true" split_words:"true"`
-	} `required:"true" split_words:"true"`
+		BaseURL       string `default:"https://slack.com" split_words:"true"`
+		Bearer        string `split_words:"true"`
+		ThreadReplies bool   `split_words:"true"`
+		MaxBlocksSize int    `split_words:"true"`
+	} `split_words:"true"`
+	// SMTP is optional, and only required when any of the notifiers sends
+	// emails. RequireTLS fails sending to servers not offering STARTTLS.
+	SMTP struct {
+		Addr       string
+		From       string
+		Username   string
+		Password   string
+		Subject    string
+		RequireTLS bool `split_words:"true"`
+	}
+	// ChatIDs maps usernames to chat member IDs, so that templates can mention
+	// users. File points to a JSON or YAML file with a username to member ID
+	// mapping, where usernames are prefixed by their provider like projects,
+	// e.g. "github:alice". LookupByEmail looks up any other users in Slack by
+	// their public email address.
+	ChatIDs struct {
+		File          string
+		LookupByEmail bool `split_words:"true"`
+	} `split_words:"true"`
+	// BusinessHours configures the working time in which the businessAge and
+	// businessHours template functions measure ages.
+	BusinessHours businessHoursConfig `split_words:"true"`
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "preview" {
+		if err := runPreview(os.Args[2:], os.Stdout, os.Stderr); err != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "runPreview: %s\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
@@ -32,7 +94,7 @@ func main() {
 		_, _ = fmt.Fprintf(os.Stderr, "envconfig: Process: %s\n", err)
 		os.Exit(1)
 	}
-	if err := run(ctx, gc, os.Stderr); err != nil {
+	if err := run(ctx, gc, time.Now(), os.Stdout, os.Stderr); err != nil {
 		_, _ = fmt.Fprintf(os.Stderr, "run: %s\n", err)
 		os.Exit(1)
 	}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/epels/preport"
+)
+
+// runPreview renders a report template using pull requests read from a
+// fixtures file, without requiring access to any provider or notifier.
+func runPreview(args []string, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("preview", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fixturesPath := fs.String("fixtures", "", "Path to a JSON or YAML file containing a list of pull requests.")
+	templatePath := fs.String("template", "", "Path to the report template. Defaults to the REPORT_TEMPLATE environment variable.")
+	order := fs.String("sort", sortCreated, "Order in which pull requests are rendered: created, updated or waiting.")
+	data := fs.String("data", templateDataPullRequests, "Data the template is rendered with: pullrequests or report.")
+	nowFlag := fs.String("now", "", "RFC 3339 timestamp ages are measured up to, for reproducible output. Defaults to the current time.")
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("flag: FlagSet.Parse: %s", err)
+	}
+	if *fixturesPath == "" {
+		return errors.New("-fixtures must be set")
+	}
+	now := time.Now()
+	if *nowFlag != "" {
+		var err error
+		if now, err = time.Parse(time.RFC3339, *nowFlag); err != nil {
+			return fmt.Errorf("time: Parse: %s", err)
+		}
+	}
+
+	// Name the template after its origin, so that parse and execution errors
+	// point to the right location.
+	name, text := "REPORT_TEMPLATE", os.Getenv("REPORT_TEMPLATE")
+	if *templatePath != "" {
+		b, err := ioutil.ReadFile(*templatePath)
+		if err != nil {
+			return fmt.Errorf("io/ioutil: ReadFile: %s", err)
+		}
+		name, text = *templatePath, string(b)
+	}
+	if text == "" {
+		return errors.New("-template or REPORT_TEMPLATE must be set")
+	}
+	// Ages are measured in the same business hours as reports are.
+	var bhConf businessHoursConfig
+	if err := envconfig.Process("business_hours", &bhConf); err != nil {
+		return fmt.Errorf("envconfig: Process: %s", err)
+	}
+	bh, err := bhConf.parse()
+	if err != nil {
+		return err
+	}
+	tmpl, err := newTemplate(name, text, templateFuncs(bh, now))
+	if err != nil {
+		return fmt.Errorf("text/template: Template.Parse: %s", err)
+	}
+
+	prs, err := readFixtures(*fixturesPath)
+	if err != nil {
+		return err
+	}
+	s, err := previewReport(prs, now).render(tmpl, *order, *data)
+	if err != nil {
+		return fmt.Errorf("report.render: %s", err)
+	}
+	if _, err := fmt.Fprintln(stdout, s); err != nil {
+		return fmt.Errorf("fmt: Fprintln: %s", err)
+	}
+	return nil
+}
+
+// previewReport reports prs as if they were fetched for a channel named
+// "preview", with a project for every project path.
+func previewReport(prs []preport.PullRequest, now time.Time) report {
+	n := notifierEntry{Channel: "preview"}
+	fetched := make(map[fetchKey][]preport.PullRequest)
+	for _, pr := range prs {
+		key := newFetchKey(pr.Project.Path, false, n.filter())
+		if _, ok := fetched[key]; !ok {
+			n.Projects = append(n.Projects, pr.Project.Path)
+		}
+		fetched[key] = append(fetched[key], pr)
+	}
+
+	a := app{now: now}
+	return a.collect(fetched, nil, n)
+}
+
+// readFixtures reads a list of pull requests from the JSON or YAML file at
+// path.
+func readFixtures(path string) ([]preport.PullRequest, error) {
+	var prs []preport.PullRequest
+	if err := decodeFile(path, &prs); err != nil {
+		return nil, err
+	}
+	return prs, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPreview(t *testing.T) {
+	const expected = `- First by alice (2021-01-01)
+- Second by bob (2021-01-02)
+
+`
+
+	t.Run("JSON", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-template", "testdata/preview.tmpl"}, &stdout, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, expected, stdout.String())
+	})
+
+	t.Run("YAML", func(t *testing.T) {
+		var stdout bytes.Buffer
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.yaml", "-template", "testdata/preview.tmpl"}, &stdout, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, expected, stdout.String())
+	})
+
+	t.Run("Template from environment", func(t *testing.T) {
+		setenv(t, "REPORT_TEMPLATE", `{{len .}} pending`)
+
+		var stdout bytes.Buffer
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json"}, &stdout, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, "2 pending\n", stdout.String())
+	})
+
+	t.Run("Fixed now", func(t *testing.T) {
+		setenv(t, "REPORT_TEMPLATE", `{{now.Format "2006-01-02"}}:{{range .}} {{.TimeOpen}}{{end}}`)
+
+		var stdout bytes.Buffer
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-now", "2021-01-03T00:00:00Z"}, &stdout, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, "2021-01-03: 39h0m0s 15h0m0s\n", stdout.String())
+	})
+
+	t.Run("Report data", func(t *testing.T) {
+		setenv(t, "REPORT_TEMPLATE", `{{.Channel}}: {{.Total}} in {{len .Projects}}`)
+
+		var stdout bytes.Buffer
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-data", "report"}, &stdout, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, "preview: 2 in 1\n", stdout.String())
+	})
+
+	t.Run("Default data", func(t *testing.T) {
+		setenv(t, "REPORT_TEMPLATE", `{{len .}} pending`)
+
+		var stdout bytes.Buffer
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-data", ""}, &stdout, io.Discard)
+		require.NoError(t, err)
+		assert.Equal(t, "2 pending\n", stdout.String())
+	})
+
+	t.Run("Unknown data", func(t *testing.T) {
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-template", "testdata/preview.tmpl", "-data", "map"}, io.Discard, io.Discard)
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid now", func(t *testing.T) {
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-template", "testdata/preview.tmpl", "-now", "yesterday"}, io.Discard, io.Discard)
+		require.Error(t, err)
+	})
+
+	t.Run("Missing fixtures", func(t *testing.T) {
+		err := runPreview([]string{"-template", "testdata/preview.tmpl"}, io.Discard, io.Discard)
+		require.Error(t, err)
+	})
+
+	t.Run("Invalid fixtures", func(t *testing.T) {
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures_invalid.json", "-template", "testdata/preview.tmpl"}, io.Discard, io.Discard)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "testdata/preview_fixtures_invalid.json:4:3")
+	})
+
+	t.Run("Invalid template", func(t *testing.T) {
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json", "-template", "testdata/preview_invalid.tmpl"}, io.Discard, io.Discard)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "testdata/preview_invalid.tmpl:2")
+	})
+
+	t.Run("Template execution failed", func(t *testing.T) {
+		setenv(t, "REPORT_TEMPLATE", `{{range .}}{{.Missing}}{{end}}`)
+
+		err := runPreview([]string{"-fixtures", "testdata/preview_fixtures.json"}, io.Discard, io.Discard)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "REPORT_TEMPLATE:1:13")
+	})
+}
+
+// setenv sets an environment variable for the duration of the test, and
+// restores its original value when the test finishes.
+func setenv(t *testing.T, key, value string) {
+	t.Helper()
+
+	orig, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatalf("os: Setenv: %s", err)
+	}
+	t.Cleanup(func() {
+		if !ok {
+			_ = os.Unsetenv(key)
+			return
+		}
+		_ = os.Setenv(key, orig)
+	})
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/epels/preport"
+)
+
+const (
+	// templateDataPullRequests renders templates with the list of pull
+	// requests, as reports have been rendered before templateDataReport.
+	templateDataPullRequests = "pullrequests"
+	// templateDataReport renders templates with a report.
+	templateDataReport = "report"
+)
+
+// report is the data templates are rendered with when using
+// templateDataReport.
+type report struct {
+	Notifier, Channel string
+	GeneratedAt       time.Time
+	// PullRequests lists the pull requests of all projects, leaving out those
+	// listed more than once.
+	PullRequests []preport.PullRequest
+	Total        int
+	Projects     []projectReport
+	// Complete reports whether all projects were fetched successfully.
+	Complete bool
+}
+
+// projectReport lists the pull requests of a configured project or group.
+type projectReport struct {
+	// Ref is the project or group as referred to in configuration, e.g.
+	// "github:owner/repo".
+	Ref          string
+	Group        bool
+	PullRequests []preport.PullRequest
+	Total        int
+	// Error describes why the pull requests could not be fetched, if so.
+	Error string
+}
+
+// repositoryReport lists the pull requests of a single project.
+type repositoryReport struct {
+	Project      preport.Project
+	PullRequests []preport.PullRequest
+	Total        int
+}
+
+// Repositories groups the pull requests of r by their project, ordered by
+// path and provider, e.g. to render a section per repository. Unlike
+// Projects, groups are split up into the projects they contain.
+func (r report) Repositories() []repositoryReport {
+	var repos []repositoryReport
+	index := make(map[string]int)
+	for _, pr := range r.PullRequests {
+		key := pr.Project.Provider + ":" + pr.Project.Path
+		i, ok := index[key]
+		if !ok {
+			i = len(repos)
+			index[key] = i
+			repos = append(repos, repositoryReport{Project: pr.Project})
+		}
+		repos[i].PullRequests = append(repos[i].PullRequests, pr)
+		repos[i].Total++
+	}
+	sort.SliceStable(repos, func(i, j int) bool {
+		pi, pj := repos[i].Project, repos[j].Project
+		if pi.Path != pj.Path {
+			return pi.Path < pj.Path
+		}
+		return pi.Provider < pj.Provider
+	})
+	return repos
+}
+
+// Errors lists the errors of the projects which could not be fetched.
+func (r report) Errors() []string {
+	var errs []string
+	for _, p := range r.Projects {
+		if p.Error != "" {
+			errs = append(errs, p.Ref+": "+p.Error)
+		}
+	}
+	return errs
+}
+
+// collect gathers the fetched pull requests of the projects and groups of n
+// into a report, leaving out excluded projects. Projects which could not be
+// fetched are included with the error in errs.
+func (a *app) collect(fetched map[fetchKey][]preport.PullRequest, errs map[fetchKey]error, n notifierEntry) report {
+	r := report{
+		Channel:     n.Channel,
+		GeneratedAt: a.now,
+		Complete:    true,
+	}
+	seen := make(map[string]bool)
+	add := func(p *projectReport, pr preport.PullRequest) {
+		pr.AsOf = a.now
+		p.PullRequests = append(p.PullRequests, pr)
+		p.Total++
+		if pr.URL != "" && seen[pr.URL] {
+			return
+		}
+		seen[pr.URL] = true
+		r.PullRequests = append(r.PullRequests, pr)
+		r.Total++
+	}
+
+	filter := n.filter()
+	var projects []projectReport
+	for _, p := range n.Projects {
+		projects = append(projects, projectReport{Ref: p})
+	}
+	for _, g := range n.Groups {
+		projects = append(projects, projectReport{Ref: g.Group, Group: true})
+	}
+	for i := range projects {
+		p := &projects[i]
+		key := newFetchKey(p.Ref, p.Group, filter)
+		fetchedPRs, ok := fetched[key]
+		if !ok {
+			p.Error = "not fetched"
+			if err := errs[key]; err != nil {
+				p.Error = err.Error()
+			}
+			r.Complete = false
+			continue
+		}
+
+		for _, pr := range fetchedPRs {
+			if p.Group && n.Groups[i-len(n.Projects)].excludes(pr.Project.Path) {
+				continue
+			}
+			add(p, pr)
+		}
+	}
+	r.Projects = projects
+	return r
+}
+
+// render sorts r in order, and renders tmpl with the data td refers to. Both
+// reports and previews are rendered through it.
+func (r report) render(tmpl *template.Template, order, td string) (string, error) {
+	if err := r.sort(order); err != nil {
+		return "", err
+	}
+	var data interface{} = r.PullRequests
+	if td == templateDataReport {
+		data = r
+	} else if err := validateTemplateData(td); err != nil {
+		return "", err
+	}
+	return renderTemplate(tmpl, data)
+}
+
+// validateTemplateData checks that td refers to known template data, where
+// an empty string refers to templateDataPullRequests.
+func validateTemplateData(td string) error {
+	switch td {
+	case "", templateDataPullRequests, templateDataReport:
+		return nil
+	}
+	return fmt.Errorf("unknown template data: %q", td)
+}
+
+// validateSort checks that order refers to a known sort order, where an empty
+// string refers to sortCreated.
+func validateSort(order string) error {
+	switch order {
+	case "", sortCreated, sortUpdated, sortWaiting:
+		return nil
+	}
+	return fmt.Errorf("unknown sort order: %q", order)
+}
+
+// sort sorts the pull requests of r from oldest to newest by the timestamp
+// order refers to, defaulting to sortCreated.
+func (r report) sort(order string) error {
+	lists := [][]preport.PullRequest{r.PullRequests}
+	for _, p := range r.Projects {
+		lists = append(lists, p.PullRequests)
+	}
+	for _, prs := range lists {
+		if err := sortPullRequests(prs, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortPullRequests(prs []preport.PullRequest, order string) error {
+	if err := validateSort(order); err != nil {
+		return err
+	}
+	switch order {
+	case "", sortCreated:
+		sort.Sort(preport.PullRequestsByCreatedAt(prs))
+	case sortUpdated:
+		sort.Sort(preport.PullRequestsByUpdatedAt(prs))
+	case sortWaiting:
+		sort.Sort(preport.PullRequestsByWaitingSince(prs))
+	}
+	return nil
+}
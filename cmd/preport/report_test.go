@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport"
+)
+
+func TestReport_sort(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2021, 1, d, 0, 0, 0, 0, time.UTC) }
+	prs := []preport.PullRequest{
+		{Title: "a", CreatedAt: day(1), UpdatedAt: day(6), WaitingSince: day(4)},
+		{Title: "b", CreatedAt: day(2), UpdatedAt: day(4)},
+		{Title: "c", CreatedAt: day(3), UpdatedAt: day(5), WaitingSince: day(3)},
+	}
+	titles := func(prs []preport.PullRequest) string {
+		var s string
+		for _, pr := range prs {
+			s += pr.Title
+		}
+		return s
+	}
+
+	for order, want := range map[string]string{
+		"":        "abc",
+		"created": "abc",
+		"updated": "bca",
+		"waiting": "bca",
+	} {
+		r := report{
+			PullRequests: append([]preport.PullRequest(nil), prs...),
+			Projects:     []projectReport{{PullRequests: append([]preport.PullRequest(nil), prs...)}},
+		}
+		require.NoError(t, r.sort(order))
+		assert.Equal(t, want, titles(r.PullRequests), "Unexpected order for %q", order)
+		assert.Equal(t, want, titles(r.Projects[0].PullRequests), "Unexpected project order for %q", order)
+	}
+
+	require.Error(t, report{}.sort("oldest"))
+}
+
+func TestPreviewReport(t *testing.T) {
+	now := time.Date(2021, 1, 4, 0, 0, 0, 0, time.UTC)
+	r := previewReport([]preport.PullRequest{
+		{Title: "a", URL: "x/1", Project: preport.Project{Path: "g/x"}},
+		{Title: "b", URL: "y/1", Project: preport.Project{Path: "g/y"}},
+		{Title: "c", URL: "x/2", Project: preport.Project{Path: "g/x"}},
+	}, now)
+
+	assert.Equal(t, "preview", r.Channel)
+	assert.Equal(t, now, r.GeneratedAt)
+	assert.Equal(t, 3, r.Total)
+	assert.True(t, r.Complete)
+	assert.Empty(t, r.Errors())
+	require.Len(t, r.Projects, 2)
+	assert.Equal(t, "g/x", r.Projects[0].Ref)
+	assert.Equal(t, 2, r.Projects[0].Total)
+	assert.Equal(t, "g/y", r.Projects[1].Ref)
+	assert.Equal(t, now, r.Projects[1].PullRequests[0].AsOf)
+}
+
+func TestReport_Repositories(t *testing.T) {
+	x := preport.Project{Path: "g/x", Name: "x", WebURL: "https://gitlab.com/g/x"}
+	y := preport.Project{Path: "g/y", Name: "y", WebURL: "https://gitlab.com/g/y"}
+	r := report{PullRequests: []preport.PullRequest{
+		{Title: "a", Project: y},
+		{Title: "b", Project: x},
+		{Title: "c", Project: y},
+	}}
+
+	assert.Equal(t, []repositoryReport{
+		{Project: x, PullRequests: []preport.PullRequest{{Title: "b", Project: x}}, Total: 1},
+		{Project: y, PullRequests: []preport.PullRequest{{Title: "a", Project: y}, {Title: "c", Project: y}}, Total: 2},
+	}, r.Repositories())
+}
+
+func TestReport_Repositories_providers(t *testing.T) {
+	gitlab := preport.Project{Provider: "gitlab", Path: "org/repo", Name: "repo"}
+	github := preport.Project{Provider: "github", Path: "org/repo", Name: "repo"}
+	r := report{PullRequests: []preport.PullRequest{
+		{Title: "a", Project: gitlab},
+		{Title: "b", Project: github},
+	}}
+
+	assert.Equal(t, []repositoryReport{
+		{Project: github, PullRequests: []preport.PullRequest{{Title: "b", Project: github}}, Total: 1},
+		{Project: gitlab, PullRequests: []preport.PullRequest{{Title: "a", Project: gitlab}}, Total: 1},
+	}, r.Repositories())
+}
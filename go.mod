@@ -3,7 +3,8 @@ module github.com/epels/preport
 go 1.16
 
 require (
-	github.com/kelseyhightower/envconfig v1.4.0 // indirect
-	github.com/stretchr/testify v1.7.0 // indirect
-	go.opencensus.io v0.23.0 // indirect
+	github.com/kelseyhightower/envconfig v1.4.0
+	github.com/stretchr/testify v1.7.0
+	go.opencensus.io v0.23.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,217 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"html"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/epels/preport"
+)
+
+// DefaultEmailSubject is the subject used when Email.Subject is not set.
+const DefaultEmailSubject = "Pull requests pending review"
+
+// Email sends reports over SMTP to a comma separated list of recipients, which
+// is used as the destination. STARTTLS is used when the server supports it,
+// and required with RequireTLS.
+type Email struct {
+	addr, host string
+	from       *mail.Address
+	auth       smtp.Auth
+
+	// Subject is the subject of every email. It defaults to
+	// DefaultEmailSubject.
+	Subject string
+	// TLSConfig is used for STARTTLS. It defaults to verifying the server
+	// certificate against the host of addr.
+	TLSConfig *tls.Config
+	// RequireTLS refuses to send reports in plain text to servers not
+	// offering STARTTLS, e.g. when it is stripped by an attacker.
+	RequireTLS bool
+}
+
+var _ preport.Notifier = (*Email)(nil)
+
+// NewEmail returns an Email that sends through the SMTP server at addr. PLAIN
+// authentication is used unless username is empty.
+func NewEmail(addr, from, username, password string) (*Email, error) {
+	switch "" {
+	case addr:
+		return nil, errors.New("addr must not be empty")
+	case from:
+		return nil, errors.New("from must not be empty")
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, errors.New("addr must be formatted as host:port")
+	}
+	fromAddr, err := mail.ParseAddress(from)
+	if err != nil {
+		return nil, errors.New("from must be a valid email address")
+	}
+
+	e := Email{
+		addr: addr,
+		host: host,
+		from: fromAddr,
+	}
+	if username != "" {
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &e, nil
+}
+
+// Notify sends content as a multipart email with a plain text and an HTML
+// part to recipients.
+func (e *Email) Notify(ctx context.Context, recipients, content string) error {
+	var to []*mail.Address
+	for _, r := range strings.Split(recipients, ",") {
+		a, err := mail.ParseAddress(strings.TrimSpace(r))
+		if err != nil {
+			return fmt.Errorf("net/mail: ParseAddress: %s", err)
+		}
+		to = append(to, a)
+	}
+
+	msg, err := e.message(to, content)
+	if err != nil {
+		return err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return fmt.Errorf("net: Dialer.DialContext: %s", err)
+	}
+	// Deadline is a generous duration intended as a fallback for when the
+	// caller does not provide a context with a sensible deadline.
+	deadline := time.Now().Add(30 * time.Second)
+	if dl, ok := ctx.Deadline(); ok {
+		deadline = dl
+	}
+	if err := conn.SetDeadline(deadline); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("net: Conn.SetDeadline: %s", err)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+		case <-done:
+		}
+	}()
+
+	c, err := smtp.NewClient(conn, e.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("net/smtp: NewClient: %s", err)
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		tlsConf := e.TLSConfig
+		if tlsConf == nil {
+			tlsConf = &tls.Config{ServerName: e.host}
+		}
+		if err := c.StartTLS(tlsConf); err != nil {
+			return fmt.Errorf("net/smtp: Client.StartTLS: %s", err)
+		}
+	} else if e.RequireTLS {
+		return errors.New("server does not support STARTTLS")
+	}
+	if e.auth != nil {
+		if err := c.Auth(e.auth); err != nil {
+			return fmt.Errorf("net/smtp: Client.Auth: %s", err)
+		}
+	}
+	if err := c.Mail(e.from.Address); err != nil {
+		return fmt.Errorf("net/smtp: Client.Mail: %s", err)
+	}
+	for _, a := range to {
+		if err := c.Rcpt(a.Address); err != nil {
+			return fmt.Errorf("net/smtp: Client.Rcpt: %s", err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("net/smtp: Client.Data: %s", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("net/smtp: Client.Data: Write: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("net/smtp: Client.Data: Close: %s", err)
+	}
+	if err := c.Quit(); err != nil {
+		return fmt.Errorf("net/smtp: Client.Quit: %s", err)
+	}
+	return nil
+}
+
+func (e *Email) message(to []*mail.Address, content string) ([]byte, error) {
+	subject := DefaultEmailSubject
+	if e.Subject != "" {
+		subject = e.Subject
+	}
+	recipients := make([]string, 0, len(to))
+	for _, a := range to {
+		recipients = append(recipients, a.String())
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	htmlContent := "<html><body><pre style=\"font-family: sans-serif; white-space: pre-wrap;\">" +
+		html.EscapeString(content) +
+		"</pre></body></html>"
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", content},
+		{"text/html; charset=utf-8", htmlContent},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("mime/multipart: Writer.CreatePart: %s", err)
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, fmt.Errorf("mime/quotedprintable: Writer.Write: %s", err)
+		}
+		if err := qw.Close(); err != nil {
+			return nil, fmt.Errorf("mime/quotedprintable: Writer.Close: %s", err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("mime/multipart: Writer.Close: %s", err)
+	}
+
+	var msg bytes.Buffer
+	for _, h := range [][2]string{
+		{"From", e.from.String()},
+		{"To", strings.Join(recipients, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": mw.Boundary()})},
+	} {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h[0], h[1])
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
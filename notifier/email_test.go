@@ -0,0 +1,278 @@
+package notifier_test
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport/notifier"
+)
+
+func TestNewEmail(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		e, err := notifier.NewEmail("localhost:25", "preport@example.com", "user", "pass")
+		require.NoError(t, err)
+		assert.NotNil(t, e)
+	})
+	t.Run("Invalid addr", func(t *testing.T) {
+		_, err := notifier.NewEmail("localhost", "preport@example.com", "", "")
+		require.Error(t, err)
+	})
+	t.Run("Invalid from", func(t *testing.T) {
+		_, err := notifier.NewEmail("localhost:25", "preport", "", "")
+		require.Error(t, err)
+	})
+	t.Run("Empty addr", func(t *testing.T) {
+		_, err := notifier.NewEmail("", "preport@example.com", "", "")
+		require.Error(t, err)
+	})
+	t.Run("Empty from", func(t *testing.T) {
+		_, err := notifier.NewEmail("localhost:25", "", "", "")
+		require.Error(t, err)
+	})
+}
+
+func TestEmail(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		srv := newSMTPServer(t)
+
+		e, err := notifier.NewEmail(srv.addr, "Preport <preport@example.com>", "user", "pass")
+		require.NoError(t, err)
+		e.Subject = "Daily digest"
+
+		err = e.Notify(context.Background(), "alice@example.com, Bob <bob@example.com>", "Review <this> please")
+		require.NoError(t, err)
+
+		srv.wait()
+		assert.Equal(t, "\x00user\x00pass", srv.auth)
+		assert.Equal(t, "<preport@example.com>", srv.from)
+		assert.Equal(t, []string{"<alice@example.com>", "<bob@example.com>"}, srv.rcpts)
+
+		msg, err := mail.ReadMessage(strings.NewReader(srv.data))
+		require.NoError(t, err)
+		assert.Equal(t, "Daily digest", msg.Header.Get("Subject"))
+		assert.Equal(t, `"Preport" <preport@example.com>`, msg.Header.Get("From"))
+		assert.Equal(t, `<alice@example.com>, "Bob" <bob@example.com>`, msg.Header.Get("To"))
+
+		mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		require.NoError(t, err)
+		assert.Equal(t, "multipart/alternative", mediaType)
+
+		mr := multipart.NewReader(msg.Body, params["boundary"])
+		text, err := mr.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "text/plain; charset=utf-8", text.Header.Get("Content-Type"))
+		b, err := ioutil.ReadAll(text)
+		require.NoError(t, err)
+		assert.Equal(t, "Review <this> please", string(b))
+
+		html, err := mr.NextPart()
+		require.NoError(t, err)
+		assert.Equal(t, "text/html; charset=utf-8", html.Header.Get("Content-Type"))
+		b, err = ioutil.ReadAll(html)
+		require.NoError(t, err)
+		assert.Contains(t, string(b), "Review &lt;this&gt; please")
+	})
+
+	t.Run("STARTTLS", func(t *testing.T) {
+		srv := newSMTPServer(t)
+		srv.startTLS = true
+
+		e, err := notifier.NewEmail(srv.addr, "preport@example.com", "user", "pass")
+		require.NoError(t, err)
+		e.TLSConfig = srv.clientTLSConfig()
+		e.RequireTLS = true
+
+		err = e.Notify(context.Background(), "alice@example.com", "Just testing")
+		require.NoError(t, err)
+
+		srv.wait()
+		assert.True(t, srv.tls)
+		assert.Equal(t, "\x00user\x00pass", srv.auth)
+		assert.Equal(t, []string{"<alice@example.com>"}, srv.rcpts)
+		assert.Contains(t, srv.data, "Just testing")
+	})
+
+	t.Run("STARTTLS required", func(t *testing.T) {
+		srv := newSMTPServer(t)
+
+		e, err := notifier.NewEmail(srv.addr, "preport@example.com", "", "")
+		require.NoError(t, err)
+		e.RequireTLS = true
+
+		err = e.Notify(context.Background(), "alice@example.com", "Just testing")
+		require.EqualError(t, err, "server does not support STARTTLS")
+
+		srv.wait()
+		assert.Empty(t, srv.from)
+		assert.Empty(t, srv.data)
+	})
+
+	t.Run("Invalid recipient", func(t *testing.T) {
+		e, err := notifier.NewEmail("localhost:25", "preport@example.com", "", "")
+		require.NoError(t, err)
+
+		err = e.Notify(context.Background(), "alice", "Just testing")
+		require.Error(t, err)
+	})
+
+	t.Run("Recipient rejected", func(t *testing.T) {
+		srv := newSMTPServer(t)
+		srv.rejectRcpt = true
+
+		e, err := notifier.NewEmail(srv.addr, "preport@example.com", "", "")
+		require.NoError(t, err)
+
+		err = e.Notify(context.Background(), "alice@example.com", "Just testing")
+		require.Error(t, err)
+	})
+
+	t.Run("Dial failed", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := l.Addr().String()
+		require.NoError(t, l.Close())
+
+		e, err := notifier.NewEmail(addr, "preport@example.com", "", "")
+		require.NoError(t, err)
+
+		err = e.Notify(context.Background(), "alice@example.com", "Just testing")
+		require.Error(t, err)
+	})
+}
+
+// smtpServer is a minimal SMTP stand-in that accepts a single session, and
+// records what it received. With startTLS, it offers STARTTLS using the
+// certificate of an httptest server.
+type smtpServer struct {
+	addr       string
+	rejectRcpt bool
+	startTLS   bool
+
+	cert             *httptest.Server
+	wg               sync.WaitGroup
+	tls              bool
+	auth, from, data string
+	rcpts            []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	srv := smtpServer{
+		addr: l.Addr().String(),
+		cert: httptest.NewTLSServer(http.NotFoundHandler()),
+	}
+	t.Cleanup(srv.cert.Close)
+	srv.wg.Add(1)
+	go func() {
+		defer srv.wg.Done()
+
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		srv.serve(t, conn)
+	}()
+	return &srv
+}
+
+// clientTLSConfig returns a TLS config trusting the certificate of s.
+func (s *smtpServer) clientTLSConfig() *tls.Config {
+	pool := x509.NewCertPool()
+	pool.AddCert(s.cert.Certificate())
+	return &tls.Config{RootCAs: pool, ServerName: "example.com"}
+}
+
+func (s *smtpServer) serve(t *testing.T, conn net.Conn) {
+	c := textproto.NewConn(conn)
+	reply := func(format string, args ...interface{}) {
+		if err := c.PrintfLine(format, args...); err != nil {
+			t.Errorf("textproto: Conn.PrintfLine: %s", err)
+		}
+	}
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		arg := strings.TrimSpace(strings.TrimPrefix(line, strings.SplitN(line, " ", 2)[0]))
+
+		switch cmd {
+		case "EHLO":
+			reply("250-localhost")
+			if s.startTLS && !s.tls {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN")
+		case "STARTTLS":
+			reply("220 Ready to start TLS")
+			tc := tls.Server(conn, s.cert.TLS)
+			if err := tc.Handshake(); err != nil {
+				t.Errorf("crypto/tls: Conn.Handshake: %s", err)
+				return
+			}
+			s.tls = true
+			c = textproto.NewConn(tc)
+		case "AUTH":
+			b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			if err != nil {
+				t.Errorf("encoding/base64: Encoding.DecodeString: %s", err)
+			}
+			s.auth = string(b)
+			reply("235 Authentication succeeded")
+		case "MAIL":
+			s.from = strings.TrimPrefix(arg, "FROM:")
+			reply("250 OK")
+		case "RCPT":
+			if s.rejectRcpt {
+				reply("550 No such user")
+				continue
+			}
+			s.rcpts = append(s.rcpts, strings.TrimPrefix(arg, "TO:"))
+			reply("250 OK")
+		case "DATA":
+			reply("354 Go ahead")
+			b, err := ioutil.ReadAll(bufio.NewReader(c.DotReader()))
+			if err != nil {
+				t.Errorf("io/ioutil: ReadAll: %s", err)
+			}
+			s.data = string(b)
+			reply("250 OK")
+		case "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("502 Command not implemented")
+		}
+	}
+}
+
+// wait blocks until the session has ended.
+func (s *smtpServer) wait() {
+	s.wg.Wait()
+}
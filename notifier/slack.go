@@ -10,16 +10,34 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/epels/preport"
 )
 
 type Slack struct {
 	httpc           *http.Client
 	baseURL, bearer string
+
+	// BlockKit makes Notify interpret reports as Block Kit blocks rather than
+	// mrkdwn, see parseBlocks. Reports not formatted as JSON, such as a plain
+	// text message for when there is nothing to report, are still posted as
+	// mrkdwn.
+	BlockKit bool
+	// ThreadReplies posts the messages following the first one of a report
+	// split up into several messages as replies in its thread.
+	ThreadReplies bool
+	// MaxBlocksSize is the size in bytes the encoded blocks of a message are
+	// kept within. It defaults to DefaultSlackMaxBlocksSize.
+	MaxBlocksSize int
 }
 
+var _ preport.Notifier = (*Slack)(nil)
+
 func NewSlack(baseURL, bearer string) (*Slack, error) {
 	switch "" {
 	case baseURL:
@@ -43,46 +61,59 @@ func NewSlack(baseURL, bearer string) (*Slack, error) {
 	}, nil
 }
 
+// WithBlockKit returns a copy of s with BlockKit set, which shares its HTTP
+// client.
+func (s *Slack) WithBlockKit() *Slack {
+	c := *s
+	c.BlockKit = true
+	return &c
+}
+
+// Notify posts content to channel. Reports exceeding the limits of a single
+// message are split up into several messages, which are posted in order. With
+// ThreadReplies, all but the first message are posted in its thread.
 func (s *Slack) Notify(ctx context.Context, channel, content string) error {
-	type textBlock struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	}
-	type block struct {
-		Type string    `json:"type"`
-		Text textBlock `json:"text"`
-	}
-	reqData := struct {
-		Channel string  `json:"channel"`
-		Blocks  []block `json:"blocks"`
-	}{
-		Channel: channel,
-		Blocks: []block{
-			{
-				Type: "section",
-				Text: textBlock{
-					Type: "mrkdwn",
-					Text: content,
-				},
-			},
-		},
+	msgs, err := s.messages(channel, content)
+	if err != nil {
+		return err
 	}
 
-	b, err := json.Marshal(reqData)
+	var threadTS string
+	for i, m := range msgs {
+		if s.ThreadReplies && i > 0 {
+			m.ThreadTS = threadTS
+		}
+		ts, err := s.post(ctx, m)
+		if err != nil {
+			if len(msgs) == 1 {
+				return err
+			}
+			return fmt.Errorf("message %d of %d: %s", i+1, len(msgs), err)
+		}
+		if i == 0 {
+			threadTS = ts
+		}
+	}
+	return nil
+}
+
+// post posts m, and returns the timestamp identifying the message.
+func (s *Slack) post(ctx context.Context, m slackMessage) (string, error) {
+	b, err := json.Marshal(m)
 	if err != nil {
-		return fmt.Errorf("encoding/json: Marshal: %s", err)
+		return "", fmt.Errorf("encoding/json: Marshal: %s", err)
 	}
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+"/api/chat.postMessage", bytes.NewReader(b))
 	if err != nil {
-		return fmt.Errorf("net/http: NewRequestWithContext: %s", err)
+		return "", fmt.Errorf("net/http: NewRequestWithContext: %s", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+s.bearer)
 	req.Header.Set("Content-Type", "application/json; charset=utf-8")
 
 	res, err := s.httpc.Do(req)
 	if err != nil {
-		return fmt.Errorf("net/http: Client.Do: %s", err)
+		return "", fmt.Errorf("net/http: Client.Do: %s", err)
 	}
 	defer func() {
 		if err := res.Body.Close(); err != nil {
@@ -90,20 +121,403 @@ func (s *Slack) Notify(ctx context.Context, channel, content string) error {
 		}
 	}()
 	if res.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		return "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
 	}
 
 	b, err = ioutil.ReadAll(res.Body)
 	if err != nil {
-		return fmt.Errorf("io/ioutil: ReadAll: %s", err)
+		return "", fmt.Errorf("io/ioutil: ReadAll: %s", err)
 	}
 
-	var resData struct{ OK bool }
+	var resData struct {
+		OK bool
+		TS string
+	}
 	if err := json.Unmarshal(b, &resData); err != nil {
-		return fmt.Errorf("encoding/json: Unmarshal: %s", err)
+		return "", fmt.Errorf("encoding/json: Unmarshal: %s", err)
 	}
 	if !resData.OK {
-		return fmt.Errorf("request was not successful with body: %q", b)
+		return "", fmt.Errorf("request was not successful with body: %q", b)
 	}
-	return nil
+	return resData.TS, nil
+}
+
+// Payload returns the JSON request bodies Notify posts to Slack, separated by
+// newlines when the report is split up into several messages. Thread
+// timestamps are only known once posted, and are therefore left out.
+func (s *Slack) Payload(channel, content string) ([]byte, error) {
+	msgs, err := s.messages(channel, content)
+	if err != nil {
+		return nil, err
+	}
+
+	var payloads [][]byte
+	for _, m := range msgs {
+		b, err := json.Marshal(m)
+		if err != nil {
+			return nil, fmt.Errorf("encoding/json: Marshal: %s", err)
+		}
+		payloads = append(payloads, b)
+	}
+	return bytes.Join(payloads, []byte("\n")), nil
+}
+
+// Limits of a single Slack message, see
+// https://api.slack.com/reference/block-kit/blocks.
+const (
+	maxBlocks      = 50
+	maxSectionText = 3000
+)
+
+// DefaultSlackMaxBlocksSize is the default of Slack.MaxBlocksSize. Slack
+// rejects messages with blocks that are too long as "msg_blocks_too_long",
+// without documenting the limit, so it is a conservative budget well below the
+// 40,000 characters Slack truncates the text of messages at.
+const DefaultSlackMaxBlocksSize = 16000
+
+// SlackBoundary separates the parts of a mrkdwn report that must not be split
+// up over several sections or messages, e.g. the lines of a pull request. It
+// is left out of the posted text. Reports without it are split up at line
+// breaks.
+const SlackBoundary = "\x1e"
+
+type slackMessage struct {
+	Channel  string `json:"channel"`
+	ThreadTS string `json:"thread_ts,omitempty"`
+	// Text is the plain-text fallback used in notifications, and by clients
+	// unable to render blocks.
+	Text   string            `json:"text,omitempty"`
+	Blocks []json.RawMessage `json:"blocks"`
+}
+
+// SlackBlock is a Block Kit layout block, limited to the fields used by
+// sections, contexts and dividers.
+type SlackBlock struct {
+	Type      string       `json:"type"`
+	Text      *SlackText   `json:"text,omitempty"`
+	Accessory *SlackButton `json:"accessory,omitempty"`
+	Elements  []SlackText  `json:"elements,omitempty"`
+}
+
+// SlackText is a Block Kit text object, of the "mrkdwn" or "plain_text" type.
+type SlackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// SlackButton is a Block Kit button linking to URL.
+type SlackButton struct {
+	Type string    `json:"type"`
+	Text SlackText `json:"text"`
+	URL  string    `json:"url"`
+}
+
+// messages converts content into the messages to post. In Block Kit mode,
+// content formatted as JSON is parsed as blocks, and otherwise as mrkdwn.
+// Sections with text exceeding the limit are split up into several sections,
+// see splitText, and the blocks are spread over as many messages as required,
+// see splitBlocks.
+func (s *Slack) messages(channel, content string) ([]slackMessage, error) {
+	var (
+		text   string
+		blocks []json.RawMessage
+	)
+	trimmed := strings.TrimSpace(content)
+	blockKit := s.BlockKit && (strings.HasPrefix(trimmed, "[") || strings.HasPrefix(trimmed, "{"))
+	if blockKit {
+		var err error
+		if text, blocks, err = parseBlocks(content); err != nil {
+			return nil, err
+		}
+	} else {
+		for _, chunk := range splitText(content, maxSectionText) {
+			b, err := json.Marshal(SlackBlock{
+				Type: "section",
+				Text: &SlackText{Type: "mrkdwn", Text: chunk},
+			})
+			if err != nil {
+				return nil, fmt.Errorf("encoding/json: Marshal: %s", err)
+			}
+			blocks = append(blocks, b)
+		}
+	}
+
+	var msgs []slackMessage
+	maxSize := DefaultSlackMaxBlocksSize
+	if s.MaxBlocksSize != 0 {
+		maxSize = s.MaxBlocksSize
+	}
+	for _, bs := range splitBlocks(blocks, maxSize) {
+		msgs = append(msgs, slackMessage{Channel: channel, Blocks: bs})
+	}
+	if len(msgs) == 0 {
+		return nil, errors.New("report has no blocks")
+	}
+
+	if blockKit {
+		for i := range msgs {
+			msgs[i].Text = fallbackText(msgs[i].Blocks)
+		}
+		if text != "" {
+			msgs[0].Text = text
+		}
+	}
+	return msgs, nil
+}
+
+// splitBlocks spreads blocks over messages within the limits, keeping the
+// encoded blocks of every message within maxSize bytes. Where a message has to
+// be cut, it is cut at its last divider, which is dropped, so that the blocks
+// of a pull request are not spread over two messages.
+func splitBlocks(blocks []json.RawMessage, maxSize int) [][]json.RawMessage {
+	var msgs [][]json.RawMessage
+	for len(blocks) > 0 {
+		n, size := 0, 0
+		for n < len(blocks) && n < maxBlocks && (n == 0 || size+len(blocks[n]) <= maxSize) {
+			size += len(blocks[n])
+			n++
+		}
+
+		next := n
+		if n < len(blocks) {
+			for i := n; i > 0; i-- {
+				if isDivider(blocks[i]) {
+					n, next = i, i+1
+					break
+				}
+			}
+		}
+		msgs = append(msgs, blocks[:n])
+		blocks = blocks[next:]
+	}
+	return msgs
+}
+
+func isDivider(b json.RawMessage) bool {
+	var block struct{ Type string }
+	return json.Unmarshal(b, &block) == nil && block.Type == "divider"
+}
+
+// parseBlocks parses content formatted either as a JSON array of blocks, or as
+// an object with blocks and an optional plain-text fallback:
+//
+//	{"text": "3 pull requests pending review", "blocks": [...]}
+func parseBlocks(content string) (string, []json.RawMessage, error) {
+	var msg struct {
+		Text   string
+		Blocks []json.RawMessage
+	}
+	content = strings.TrimSpace(content)
+	if strings.HasPrefix(content, "[") {
+		if err := json.Unmarshal([]byte(content), &msg.Blocks); err != nil {
+			return "", nil, fmt.Errorf("encoding/json: Unmarshal: %s", err)
+		}
+	} else if err := json.Unmarshal([]byte(content), &msg); err != nil {
+		return "", nil, fmt.Errorf("encoding/json: Unmarshal: %s", err)
+	}
+
+	var blocks []json.RawMessage
+	for _, b := range msg.Blocks {
+		split, err := splitSection(b)
+		if err != nil {
+			return "", nil, err
+		}
+		blocks = append(blocks, split...)
+	}
+	return msg.Text, blocks, nil
+}
+
+// splitSection splits a section block with text exceeding the limit into
+// several sections. Any other fields, such as an accessory, are kept on the
+// first one. Other blocks are returned as is.
+func splitSection(b json.RawMessage) ([]json.RawMessage, error) {
+	var block struct {
+		Type string
+		Text *SlackText
+	}
+	if err := json.Unmarshal(b, &block); err != nil {
+		return nil, fmt.Errorf("encoding/json: Unmarshal: %s", err)
+	}
+	if block.Type != "section" || block.Text == nil || utf8.RuneCountInString(block.Text.Text) <= maxSectionText {
+		return []json.RawMessage{b}, nil
+	}
+
+	var first map[string]interface{}
+	if err := json.Unmarshal(b, &first); err != nil {
+		return nil, fmt.Errorf("encoding/json: Unmarshal: %s", err)
+	}
+	var blocks []json.RawMessage
+	for i, chunk := range splitText(block.Text.Text, maxSectionText) {
+		var v interface{} = SlackBlock{
+			Type: "section",
+			Text: &SlackText{Type: block.Text.Type, Text: chunk},
+		}
+		if i == 0 {
+			first["text"] = SlackText{Type: block.Text.Type, Text: chunk}
+			v = first
+		}
+		split, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("encoding/json: Marshal: %s", err)
+		}
+		blocks = append(blocks, split)
+	}
+	return blocks, nil
+}
+
+// fallbackText joins the texts of the sections and headers among blocks.
+func fallbackText(blocks []json.RawMessage) string {
+	var texts []string
+	for _, b := range blocks {
+		var block struct {
+			Type string
+			Text *SlackText
+		}
+		if err := json.Unmarshal(b, &block); err != nil || block.Text == nil {
+			continue
+		}
+		if block.Type == "section" || block.Type == "header" {
+			texts = append(texts, block.Text.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// splitText splits s into chunks of at most max characters, cutting only at
+// SlackBoundary where s contains it, and at line breaks otherwise. Parts too
+// long for a single chunk are cut up by cutText.
+func splitText(s string, max int) []string {
+	sep, join := "\n", "\n"
+	if strings.Contains(s, SlackBoundary) {
+		sep, join = SlackBoundary, ""
+	}
+
+	var (
+		chunks []string
+		chunk  string
+		n      int
+	)
+	for i, part := range strings.Split(s, sep) {
+		for j, piece := range cutText(part, max) {
+			pn := utf8.RuneCountInString(piece)
+			if i+j == 0 {
+				chunk, n = piece, pn
+				continue
+			}
+			if n+len(join)+pn <= max {
+				chunk += join + piece
+				n += len(join) + pn
+				continue
+			}
+			chunks = append(chunks, chunk)
+			chunk, n = piece, pn
+		}
+	}
+	chunks = append(chunks, chunk)
+	if sep == "\n" {
+		return chunks
+	}
+
+	// Parts usually start or end with the line breaks between them, which
+	// are redundant at the edges of sections.
+	var trimmed []string
+	for _, c := range chunks {
+		if c = strings.Trim(c, "\n"); c != "" {
+			trimmed = append(trimmed, c)
+		}
+	}
+	if len(trimmed) == 0 {
+		return []string{""}
+	}
+	return trimmed
+}
+
+// cutText cuts s into pieces of at most max characters, preferring to cut at
+// line breaks, and otherwise at spaces outside of links formatted as
+// <url|text>.
+func cutText(s string, max int) []string {
+	var pieces []string
+	for utf8.RuneCountInString(s) > max {
+		prefix := string([]rune(s)[:max])
+		i := strings.LastIndex(prefix, "\n")
+		if i <= 0 {
+			i = lastSpaceOutsideLink(prefix)
+		}
+		if i <= 0 {
+			pieces = append(pieces, prefix)
+			s = s[len(prefix):]
+			continue
+		}
+		pieces = append(pieces, s[:i])
+		s = s[i+1:]
+	}
+	return append(pieces, s)
+}
+
+// lastSpaceOutsideLink returns the index of the last space in s that is not
+// part of a link, or -1 if there is none.
+func lastSpaceOutsideLink(s string) int {
+	last, depth := -1, 0
+	for i, r := range s {
+		switch r {
+		case '<':
+			depth++
+		case '>':
+			if depth > 0 {
+				depth--
+			}
+		case ' ':
+			if depth == 0 {
+				last = i
+			}
+		}
+	}
+	return last
+}
+
+// LookupUserByEmail looks up the member ID of the Slack user with email. An
+// empty ID is returned when no user has the email.
+func (s *Slack) LookupUserByEmail(ctx context.Context, email string) (string, error) {
+	u := s.baseURL + "/api/users.lookupByEmail?" + url.Values{"email": {email}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("net/http: NewRequestWithContext: %s", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.bearer)
+
+	res, err := s.httpc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("net/http: Client.Do: %s", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Printf("%T: Close: %s", res.Body, err)
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("io/ioutil: ReadAll: %s", err)
+	}
+
+	var resData struct {
+		OK    bool
+		Error string
+		User  struct {
+			ID string
+		}
+	}
+	if err := json.Unmarshal(b, &resData); err != nil {
+		return "", fmt.Errorf("encoding/json: Unmarshal: %s", err)
+	}
+	if resData.Error == "users_not_found" {
+		return "", nil
+	}
+	if !resData.OK {
+		return "", fmt.Errorf("request was not successful with body: %q", b)
+	}
+	return resData.User.ID, nil
 }
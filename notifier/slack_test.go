@@ -1,8 +1,12 @@
 package notifier_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -51,6 +55,69 @@ func TestSlack(t *testing.T) {
 		require.NoError(t, err)
 	})
 
+	t.Run("Several messages", func(t *testing.T) {
+		var texts []string
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var p payload
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+			texts = append(texts, p.Blocks[0].Text.Text)
+
+			testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+		})
+
+		sc, err := notifier.NewSlack(ts.URL, "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		var blocks []string
+		for i := 0; i < 120; i++ {
+			blocks = append(blocks, fmt.Sprintf(`{"type": "section", "text": {"type": "mrkdwn", "text": "%d"}}`, i))
+		}
+		err = sc.Notify(context.Background(), "general", "["+strings.Join(blocks, ",")+"]")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"0", "50", "100"}, texts)
+	})
+
+	t.Run("Thread replies", func(t *testing.T) {
+		var threads []string
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			var p payload
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&p))
+			threads = append(threads, p.ThreadTS)
+
+			_, _ = fmt.Fprintf(w, `{"ok": true, "ts": "1610000000.00010%d"}`, len(threads))
+		})
+
+		sc, err := notifier.NewSlack(ts.URL, "super-secret")
+		require.NoError(t, err)
+		sc.ThreadReplies = true
+
+		line := strings.Repeat("x", 2999)
+		err = sc.Notify(context.Background(), "general", strings.TrimSuffix(strings.Repeat(line+"\n", 12), "\n"))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"", "1610000000.000101", "1610000000.000101"}, threads)
+	})
+
+	t.Run("Failed message", func(t *testing.T) {
+		var calls int
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 2 {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+		})
+
+		sc, err := notifier.NewSlack(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		line := strings.Repeat("x", 2999)
+		err = sc.Notify(context.Background(), "general", strings.TrimSuffix(strings.Repeat(line+"\n", 12), "\n"))
+		require.EqualError(t, err, "message 2 of 3: unexpected status code: 500")
+		assert.Equal(t, 2, calls)
+	})
+
 	t.Run("Round trip failed", func(t *testing.T) {
 		invalidBaseURL := "https://DF977BEA-4295-4758-AFF9-0EBCB1F509E2.fail"
 		sc, err := notifier.NewSlack(invalidBaseURL, "super-secret")
@@ -84,3 +151,308 @@ func TestSlack(t *testing.T) {
 		require.Error(t, err)
 	})
 }
+
+func TestSlack_Payload(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		b, err := sc.Payload("general", "Just testing")
+		require.NoError(t, err)
+		testutil.AssertTestdataJSONEquals(t, "testdata/ok_request.json", bytes.NewReader(b))
+	})
+
+	t.Run("Long mrkdwn", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		line := strings.Repeat("x", 999)
+		b, err := sc.Payload("general", strings.Repeat(line+"\n", 4))
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Blocks, 2)
+		assert.Equal(t, strings.Repeat(line+"\n", 2)+line, msgs[0].Blocks[0].Text.Text)
+		assert.Equal(t, line+"\n", msgs[0].Blocks[1].Text.Text)
+	})
+
+	t.Run("Many long mrkdwn lines", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		var lines []string
+		for i := 0; i < 20; i++ {
+			lines = append(lines, fmt.Sprintf("%02d", i)+strings.Repeat("x", 2997))
+		}
+		b, err := sc.Payload("general", strings.Join(lines, "\n"))
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 4)
+		var got []string
+		for _, m := range msgs {
+			assert.Len(t, m.Blocks, 5)
+			for _, b := range m.Blocks {
+				got = append(got, b.Text.Text)
+			}
+		}
+		assert.Equal(t, lines, got)
+	})
+
+	t.Run("Boundaries", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		// Every pull request spans two lines, and only fits a section with
+		// one other pull request.
+		var parts []string
+		for i := 0; i < 5; i++ {
+			parts = append(parts, fmt.Sprintf("*<https://example.com/%d|Pull request %d>*\n%s\n", i, i, strings.Repeat("x", 1200)))
+		}
+		b, err := sc.Payload("general", strings.Join(parts, notifier.SlackBoundary))
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Blocks, 3)
+		assert.Equal(t, strings.TrimSuffix(parts[0]+parts[1], "\n"), msgs[0].Blocks[0].Text.Text)
+		assert.Equal(t, strings.TrimSuffix(parts[2]+parts[3], "\n"), msgs[0].Blocks[1].Text.Text)
+		assert.Equal(t, strings.TrimSuffix(parts[4], "\n"), msgs[0].Blocks[2].Text.Text)
+	})
+
+	t.Run("Long line", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		// The line is cut at the last space before the link, rather than
+		// within it.
+		first := strings.Repeat("x ", 1490)
+		link := "<https://example.com/1|Pull request 1>"
+		b, err := sc.Payload("general", first+link)
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Blocks, 2)
+		assert.Equal(t, strings.TrimSuffix(first, " "), msgs[0].Blocks[0].Text.Text)
+		assert.Equal(t, link, msgs[0].Blocks[1].Text.Text)
+	})
+
+	t.Run("Max blocks size", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.MaxBlocksSize = 200
+
+		parts := []string{"First\n", "Second\n", "Third\n"}
+		b, err := sc.Payload("general", strings.Join(parts, notifier.SlackBoundary))
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "First\nSecond\nThird", msgs[0].Blocks[0].Text.Text)
+
+		sc.MaxBlocksSize = 50
+		b, err = sc.Payload("general", strings.Repeat("x", 2000)+"\n"+strings.Repeat("y", 2000))
+		require.NoError(t, err)
+		assert.Len(t, decodePayloads(t, b), 2)
+	})
+
+	t.Run("Block Kit", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		b, err := sc.Payload("general", `[
+  {"type": "header", "text": {"type": "plain_text", "text": "Pending review"}},
+  {"type": "section", "text": {"type": "mrkdwn", "text": "Just testing"}},
+  {"type": "divider"}
+]`)
+		require.NoError(t, err)
+		testutil.AssertTestdataJSONEquals(t, "testdata/blocks_request.json", bytes.NewReader(b))
+	})
+
+	t.Run("Block Kit with text", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		b, err := sc.Payload("general", `{"text": "1 pending", "blocks": [{"type": "divider"}]}`)
+		require.NoError(t, err)
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 1)
+		assert.Equal(t, "1 pending", msgs[0].Text)
+	})
+
+	t.Run("Block Kit long section", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		text := strings.Repeat("x", 2000) + "\n" + strings.Repeat("y", 2000)
+		b, err := sc.Payload("general", fmt.Sprintf(`[{
+  "type": "section",
+  "text": {"type": "mrkdwn", "text": %q},
+  "accessory": {"type": "button", "text": {"type": "plain_text", "text": "Review"}, "url": "https://example.com"}
+}]`, text))
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 1)
+		require.Len(t, msgs[0].Blocks, 2)
+		assert.Equal(t, strings.Repeat("x", 2000), msgs[0].Blocks[0].Text.Text)
+		assert.NotNil(t, msgs[0].Blocks[0].Accessory)
+		assert.Equal(t, strings.Repeat("y", 2000), msgs[0].Blocks[1].Text.Text)
+		assert.Nil(t, msgs[0].Blocks[1].Accessory)
+		assert.Equal(t, text, msgs[0].Text)
+	})
+
+	t.Run("Block Kit many blocks", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		var blocks []string
+		for i := 0; i < 60; i++ {
+			blocks = append(blocks, fmt.Sprintf(`{"type": "section", "text": {"type": "mrkdwn", "text": "%d"}}`, i))
+		}
+		b, err := sc.Payload("general", "["+strings.Join(blocks, ",")+"]")
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 2)
+		assert.Len(t, msgs[0].Blocks, 50)
+		assert.Len(t, msgs[1].Blocks, 10)
+		assert.Equal(t, "50", msgs[1].Blocks[0].Text.Text)
+		assert.Equal(t, "general", msgs[1].Channel)
+	})
+
+	t.Run("Block Kit split at dividers", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		var blocks []string
+		for i := 0; i < 12; i++ {
+			if i > 0 {
+				blocks = append(blocks, `{"type": "divider"}`)
+			}
+			blocks = append(blocks,
+				fmt.Sprintf(`{"type": "section", "text": {"type": "mrkdwn", "text": "%02d%s"}}`, i, strings.Repeat("x", 2000)),
+				`{"type": "context", "elements": [{"type": "mrkdwn", "text": "Open for 3 days"}]}`,
+			)
+		}
+		b, err := sc.Payload("general", "["+strings.Join(blocks, ",")+"]")
+		require.NoError(t, err)
+
+		msgs := decodePayloads(t, b)
+		require.Len(t, msgs, 2)
+		var got []string
+		for _, m := range msgs {
+			assert.Equal(t, "section", m.Blocks[0].Type)
+			assert.Equal(t, "context", m.Blocks[len(m.Blocks)-1].Type)
+			for _, b := range m.Blocks {
+				if b.Type == "section" {
+					got = append(got, b.Text.Text[:2])
+				}
+			}
+		}
+		assert.Equal(t, []string{"00", "01", "02", "03", "04", "05", "06", "07", "08", "09", "10", "11"}, got)
+	})
+
+	t.Run("Block Kit plain text", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		bc := sc.WithBlockKit()
+		assert.False(t, sc.BlockKit)
+
+		b, err := bc.Payload("general", "Just testing")
+		require.NoError(t, err)
+		testutil.AssertTestdataJSONEquals(t, "testdata/ok_request.json", bytes.NewReader(b))
+	})
+
+	t.Run("Block Kit invalid", func(t *testing.T) {
+		sc, err := notifier.NewSlack("https://example.com", "super-secret")
+		require.NoError(t, err)
+		sc.BlockKit = true
+
+		_, err = sc.Payload("general", `[{"type": `)
+		require.Error(t, err)
+		_, err = sc.Payload("general", "[]")
+		require.Error(t, err)
+	})
+}
+
+type payload struct {
+	Channel  string
+	ThreadTS string `json:"thread_ts"`
+	Text     string
+	Blocks   []struct {
+		Type string
+		Text struct {
+			Type string
+			Text string
+		}
+		Accessory interface{}
+	}
+}
+
+// decodePayloads decodes the newline separated payloads in b.
+func decodePayloads(t *testing.T, b []byte) []payload {
+	t.Helper()
+
+	var payloads []payload
+	for _, line := range bytes.Split(b, []byte("\n")) {
+		var p payload
+		if err := json.Unmarshal(line, &p); err != nil {
+			t.Fatalf("encoding/json: Unmarshal: %s", err)
+		}
+		payloads = append(payloads, p)
+	}
+	return payloads
+}
+
+func TestSlack_LookupUserByEmail(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "/api/users.lookupByEmail", r.URL.Path)
+			assert.Equal(t, "spengler@ghostbusters.example.com", r.URL.Query().Get("email"))
+			assert.Equal(t, "Bearer super-secret", r.Header.Get("Authorization"))
+
+			testutil.WriteTestdata(t, "testdata/lookup_by_email_response.json", w)
+		})
+
+		sc, err := notifier.NewSlack(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		id, err := sc.LookupUserByEmail(context.Background(), "spengler@ghostbusters.example.com")
+		require.NoError(t, err)
+		assert.Equal(t, "W012A3CDE", id)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"ok": false, "error": "users_not_found"}`))
+		})
+
+		sc, err := notifier.NewSlack(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		id, err := sc.LookupUserByEmail(context.Background(), "spengler@ghostbusters.example.com")
+		require.NoError(t, err)
+		assert.Empty(t, id)
+	})
+
+	t.Run("Unexpected response", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			testutil.WriteTestdata(t, "testdata/unexpected_response_response.json", w)
+		})
+
+		sc, err := notifier.NewSlack(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		_, err = sc.LookupUserByEmail(context.Background(), "spengler@ghostbusters.example.com")
+		require.Error(t, err)
+	})
+}
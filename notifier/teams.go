@@ -0,0 +1,134 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/epels/preport"
+)
+
+// Teams posts reports to Microsoft Teams incoming webhooks. As webhook URLs
+// identify both the team and the channel, they are used as the destination.
+type Teams struct {
+	httpc *http.Client
+}
+
+var _ preport.Notifier = (*Teams)(nil)
+
+func NewTeams() *Teams {
+	return &Teams{
+		httpc: &http.Client{
+			Transport: &ochttp.Transport{},
+			// Timeout is a generous duration intended as a fallback for when
+			// the caller does not provide a context with a sensible deadline.
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Notify posts content as an Adaptive Card to the incoming webhook at
+// webhookURL.
+func (t *Teams) Notify(ctx context.Context, webhookURL, content string) error {
+	if u, err := url.Parse(webhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return errors.New("webhookURL must be a valid http(s) URL")
+	}
+
+	b, err := t.Payload(webhookURL, content)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("net/http: NewRequestWithContext: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	res, err := t.httpc.Do(req)
+	if err != nil {
+		// The webhook URL is a credential, and is left out of the error.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("net/http: Client.Do: %s", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Printf("%T: Close: %s", res.Body, err)
+		}
+	}()
+
+	b, err = ioutil.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("io/ioutil: ReadAll: %s", err)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status code: %d with body: %q", res.StatusCode, b)
+	}
+	// Connectors report failures to deliver the message to Teams with a 200
+	// status code, so the body has to be inspected as well.
+	if strings.HasPrefix(string(b), "Microsoft Teams endpoint returned HTTP error") {
+		return fmt.Errorf("request was not successful with body: %q", b)
+	}
+	return nil
+}
+
+// Payload returns the JSON request body Notify posts to the webhook.
+func (t *Teams) Payload(webhookURL, content string) ([]byte, error) {
+	type textBlock struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+		Wrap bool   `json:"wrap"`
+	}
+	type card struct {
+		Schema  string      `json:"$schema"`
+		Type    string      `json:"type"`
+		Version string      `json:"version"`
+		Body    []textBlock `json:"body"`
+	}
+	type attachment struct {
+		ContentType string `json:"contentType"`
+		Content     card   `json:"content"`
+	}
+	reqData := struct {
+		Type        string       `json:"type"`
+		Attachments []attachment `json:"attachments"`
+	}{
+		Type: "message",
+		Attachments: []attachment{
+			{
+				ContentType: "application/vnd.microsoft.card.adaptive",
+				Content: card{
+					Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+					Type:    "AdaptiveCard",
+					Version: "1.4",
+					Body: []textBlock{
+						{
+							Type: "TextBlock",
+							Text: content,
+							Wrap: true,
+						},
+					},
+				},
+			},
+		},
+	}
+
+	b, err := json.Marshal(reqData)
+	if err != nil {
+		return nil, fmt.Errorf("encoding/json: Marshal: %s", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,70 @@
+package notifier_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport/internal/testutil"
+	"github.com/epels/preport/notifier"
+)
+
+func TestTeams(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/webhookb2/some-id", r.URL.Path)
+			assert.Equal(t, "application/json; charset=utf-8", r.Header.Get("Content-Type"))
+			testutil.AssertTestdataJSONEquals(t, "testdata/teams_ok_request.json", r.Body)
+
+			_, _ = w.Write([]byte("1"))
+		})
+
+		err := notifier.NewTeams().Notify(context.Background(), ts.URL+"/webhookb2/some-id", "Just testing")
+		require.NoError(t, err)
+	})
+
+	t.Run("Accepted", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		})
+
+		err := notifier.NewTeams().Notify(context.Background(), ts.URL, "Just testing")
+		require.NoError(t, err)
+	})
+
+	t.Run("Invalid webhookURL", func(t *testing.T) {
+		err := notifier.NewTeams().Notify(context.Background(), "ftp://example.com", "Just testing")
+		require.Error(t, err)
+	})
+
+	t.Run("Round trip failed", func(t *testing.T) {
+		invalidURL := "https://DF977BEA-4295-4758-AFF9-0EBCB1F509E2.fail/webhookb2/secret"
+		err := notifier.NewTeams().Notify(context.Background(), invalidURL, "Just testing")
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+	})
+
+	t.Run("Bad request", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Webhook message delivery failed with error: Summary or Text is required."))
+		})
+
+		err := notifier.NewTeams().Notify(context.Background(), ts.URL, "Just testing")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Summary or Text is required")
+	})
+
+	t.Run("Delivery failed", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("Microsoft Teams endpoint returned HTTP error 429 with ContextId tcid=0."))
+		})
+
+		err := notifier.NewTeams().Notify(context.Background(), ts.URL, "Just testing")
+		require.Error(t, err)
+	})
+}
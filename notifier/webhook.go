@@ -0,0 +1,106 @@
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/epels/preport"
+)
+
+// SignatureHeader is the header carrying the hex encoded HMAC-SHA256 signature
+// of the request body, prefixed by "sha256=", when a secret is configured.
+const SignatureHeader = "X-Preport-Signature"
+
+// Webhook posts reports as-is to arbitrary URLs, which are used as the
+// destination.
+type Webhook struct {
+	httpc   *http.Client
+	headers map[string]string
+	secret  string
+}
+
+var _ preport.Notifier = (*Webhook)(nil)
+
+// NewWebhook returns a Webhook that sets headers on every request, and signs
+// request bodies using secret unless it is empty.
+func NewWebhook(headers map[string]string, secret string) (*Webhook, error) {
+	for k := range headers {
+		if k == "" {
+			return nil, errors.New("header names must not be empty")
+		}
+	}
+
+	return &Webhook{
+		httpc: &http.Client{
+			Transport: &ochttp.Transport{},
+			// Timeout is a generous duration intended as a fallback for when
+			// the caller does not provide a context with a sensible deadline.
+			Timeout: 30 * time.Second,
+		},
+		headers: headers,
+		secret:  secret,
+	}, nil
+}
+
+// Notify posts content as the request body to u. The content type defaults to
+// JSON, and can be overridden through the configured headers.
+func (wh *Webhook) Notify(ctx context.Context, u, content string) error {
+	if pu, err := url.Parse(u); err != nil || (pu.Scheme != "http" && pu.Scheme != "https") {
+		return errors.New("u must be a valid http(s) URL")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader([]byte(content)))
+	if err != nil {
+		return fmt.Errorf("net/http: NewRequestWithContext: %s", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	for k, v := range wh.headers {
+		req.Header.Set(k, v)
+	}
+	if wh.secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(wh.secret, []byte(content)))
+	}
+
+	res, err := wh.httpc.Do(req)
+	if err != nil {
+		// Webhook URLs often carry tokens, so u is left out of the error.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			err = ue.Err
+		}
+		return fmt.Errorf("net/http: Client.Do: %s", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Printf("%T: Close: %s", res.Body, err)
+		}
+	}()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		b, err := ioutil.ReadAll(res.Body)
+		if err != nil {
+			return fmt.Errorf("io/ioutil: ReadAll: %s", err)
+		}
+		return fmt.Errorf("unexpected status code: %d with body: %q", res.StatusCode, b)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of body using secret, as sent in
+// the SignatureHeader. Receivers can use it to verify the origin of requests.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
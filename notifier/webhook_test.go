@@ -0,0 +1,102 @@
+package notifier_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport/internal/testutil"
+	"github.com/epels/preport/notifier"
+)
+
+func TestNewWebhook(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		wh, err := notifier.NewWebhook(map[string]string{"X-Api-Key": "key"}, "secret")
+		require.NoError(t, err)
+		assert.NotNil(t, wh)
+	})
+	t.Run("Empty header name", func(t *testing.T) {
+		_, err := notifier.NewWebhook(map[string]string{"": "key"}, "secret")
+		require.Error(t, err)
+	})
+}
+
+func TestWebhook(t *testing.T) {
+	const body = `{"text":"Just testing"}`
+
+	t.Run("OK", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, "/hooks/report", r.URL.Path)
+			assert.Equal(t, "application/json; charset=utf-8", r.Header.Get("Content-Type"))
+			assert.Equal(t, "key", r.Header.Get("X-Api-Key"))
+
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(b))
+			assert.Equal(t, "sha256="+notifier.Sign("secret", b), r.Header.Get(notifier.SignatureHeader))
+
+			w.WriteHeader(http.StatusNoContent)
+		})
+
+		wh, err := notifier.NewWebhook(map[string]string{"X-Api-Key": "key"}, "secret")
+		require.NoError(t, err)
+
+		err = wh.Notify(context.Background(), ts.URL+"/hooks/report", body)
+		require.NoError(t, err)
+	})
+
+	t.Run("Without secret", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "text/plain", r.Header.Get("Content-Type"))
+			assert.Empty(t, r.Header.Get(notifier.SignatureHeader))
+		})
+
+		wh, err := notifier.NewWebhook(map[string]string{"Content-Type": "text/plain"}, "")
+		require.NoError(t, err)
+
+		err = wh.Notify(context.Background(), ts.URL, "Just testing")
+		require.NoError(t, err)
+	})
+
+	t.Run("Invalid URL", func(t *testing.T) {
+		wh, err := notifier.NewWebhook(nil, "")
+		require.NoError(t, err)
+
+		err = wh.Notify(context.Background(), "ftp://example.com", body)
+		require.Error(t, err)
+	})
+
+	t.Run("Round trip failed", func(t *testing.T) {
+		wh, err := notifier.NewWebhook(nil, "")
+		require.NoError(t, err)
+
+		invalidURL := "https://DF977BEA-4295-4758-AFF9-0EBCB1F509E2.fail/hooks/secret?token=secret"
+		err = wh.Notify(context.Background(), invalidURL, body)
+		require.Error(t, err)
+		assert.NotContains(t, err.Error(), "secret")
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("something broke"))
+		})
+
+		wh, err := notifier.NewWebhook(nil, "")
+		require.NoError(t, err)
+
+		err = wh.Notify(context.Background(), ts.URL, body)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "something broke")
+	})
+}
+
+func TestSign(t *testing.T) {
+	// Generated using: echo -n 'hello' | openssl dgst -sha256 -hmac 'secret'
+	assert.Equal(t, "88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b", notifier.Sign("secret", []byte("hello")))
+}
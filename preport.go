@@ -1,21 +1,183 @@
 package preport
 
 import (
+	"context"
 	"time"
 )
 
+// PullRequestLister lists the open pull requests of a project, as identified
+// by the provider implementing it.
+type PullRequestLister interface {
+	ListPullRequests(ctx context.Context, project string, f Filter) ([]PullRequest, error)
+}
+
+// GroupLister lists the open pull requests of all projects in a group,
+// including those in its subgroups, as identified by the provider implementing
+// it.
+type GroupLister interface {
+	ListGroupPullRequests(ctx context.Context, group string, f Filter) ([]PullRequest, error)
+}
+
+// EmailLookup looks up the public email address of a user, as identified by
+// the provider implementing it. An empty address is returned when the user
+// has none.
+type EmailLookup interface {
+	LookupEmail(ctx context.Context, username string) (string, error)
+}
+
+// OwnerSuggester suggests the owners of the files changed by a pull request,
+// e.g. based on the CODEOWNERS file of its project, as identified by the
+// provider implementing it.
+type OwnerSuggester interface {
+	SuggestOwners(ctx context.Context, pr PullRequest) ([]Author, error)
+}
+
+// ReviewerAssigner assigns reviewers to pull requests, as identified by the
+// provider implementing it.
+type ReviewerAssigner interface {
+	AssignReviewer(ctx context.Context, pr PullRequest, username string) error
+	// CountReviews counts the open pull requests the user is a reviewer of.
+	CountReviews(ctx context.Context, username string) (int, error)
+}
+
+// Notifier delivers a rendered report to a destination, such as a chat channel,
+// as identified by the notifier implementing it.
+type Notifier interface {
+	Notify(ctx context.Context, destination, report string) error
+}
+
+// Filter narrows down the pull requests returned by a PullRequestLister; nil
+// values are not filtered on.
+type Filter struct {
+	IsDraft         *bool
+	HasAssignee     *bool
+	HasBeenApproved *bool
+	HasReviewer     *bool
+	// Labels only matches pull requests with all of the labels, and NotLabels
+	// those with none of them.
+	Labels       []string
+	NotLabels    []string
+	TargetBranch string
+	SourceBranch string
+	Milestone    string
+	// Author is the username of the author.
+	Author string
+	// Search matches pull requests by their title and description.
+	Search string
+}
+
 type PullRequest struct {
 	Title, URL string
 	Author     Author
 	CreatedAt  time.Time
+	Project    Project
+
+	// IID is the number of the pull request within its project.
+	IID         int
+	Description string
+	Labels      []string
+	Draft       bool
+	Assignees   []Author
+	Reviewers   []Author
+	// ReviewerTeams are the teams requested to review the pull request, for
+	// providers supporting it.
+	ReviewerTeams []Team
+
+	SourceBranch, TargetBranch string
+	UpdatedAt                  time.Time
+	// WaitingSince is when the pull request started waiting on a reviewer,
+	// i.e. its last activity by the author or the last time a review was
+	// requested. It is only populated by providers configured to fetch it.
+	WaitingSince time.Time
+	// Comments is the number of comments left by users, or zero when the
+	// provider does not report it.
+	Comments int
+	// Approval is only populated by providers configured to fetch it.
+	Approval Approval
+	// SuggestedOwners are the owners of the changed files who may be asked
+	// for a review. It is only populated when suggesting owners is enabled.
+	SuggestedOwners []Author
+	// AssignedReviewer is the reviewer assigned to the pull request by
+	// preport, if any.
+	AssignedReviewer *Author
+
+	// AsOf is the moment the time methods measure up to, so that reports are
+	// reproducible. The current time is used when it is zero.
+	AsOf time.Time
+}
+
+// Approval is the approval status of a pull request.
+type Approval struct {
+	Approved   bool
+	ApprovedBy []Author
+	// Left is the number of approvals still required, or zero when the
+	// provider does not report it.
+	Left int
+}
+
+// Project is the repository a pull request belongs to.
+type Project struct {
+	// Provider is the name of the provider listing the project, e.g.
+	// "github", as referred to in configuration. Paths are only unique
+	// within a provider.
+	Provider string
+	// Path is the full path of the project, e.g. "group/subgroup/project".
+	Path string
+	// Name is the last segment of Path, e.g. "project". It is not the display
+	// name of the project, which may differ on providers like GitLab.
+	Name string
+	// WebURL is the address of the project's page, e.g.
+	// "https://gitlab.com/group/subgroup/project".
+	WebURL string
 }
 
 type Author struct {
-	Username string
+	Username  string
+	Name      string
+	AvatarURL string
+	WebURL    string
+	// ChatID identifies the author in the chat tool reports are sent to, e.g.
+	// a Slack member ID, so that templates can mention them. It is empty when
+	// unknown.
+	ChatID string
+}
+
+// Team is a group of users that can be requested to review as a whole.
+type Team struct {
+	// Slug identifies the team within its organization, e.g. "backend".
+	Slug   string
+	Name   string
+	WebURL string
 }
 
 func (p PullRequest) TimeOpen() time.Duration {
-	return time.Since(p.CreatedAt).Round(time.Second)
+	return p.since(p.CreatedAt)
+}
+
+// TimeSinceUpdate is the time passed since the pull request was last updated.
+func (p PullRequest) TimeSinceUpdate() time.Duration {
+	return p.since(p.UpdatedAt)
+}
+
+// TimeWaiting is the time the pull request has been waiting on a reviewer. It
+// equals TimeOpen when WaitingSince is not populated.
+func (p PullRequest) TimeWaiting() time.Duration {
+	return p.since(p.waitingSince())
+}
+
+func (p PullRequest) since(t time.Time) time.Duration {
+	now := p.AsOf
+	if now.IsZero() {
+		now = time.Now()
+	}
+	return now.Sub(t).Round(time.Second)
+}
+
+func (p PullRequest) waitingSince() time.Time {
+	if p.WaitingSince.IsZero() {
+		return p.CreatedAt
+	}
+	return p.WaitingSince
 }
 
 // PullRequestsBySortedAt providers a sorter based on CreatedAt timestamp, from
@@ -25,3 +187,21 @@ type PullRequestsByCreatedAt []PullRequest
 func (ps PullRequestsByCreatedAt) Len() int           { return len(ps) }
 func (ps PullRequestsByCreatedAt) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
 func (ps PullRequestsByCreatedAt) Less(i, j int) bool { return ps[i].CreatedAt.Before(ps[j].CreatedAt) }
+
+// PullRequestsByUpdatedAt provides a sorter based on UpdatedAt timestamp, from
+// least to most recently updated.
+type PullRequestsByUpdatedAt []PullRequest
+
+func (ps PullRequestsByUpdatedAt) Len() int           { return len(ps) }
+func (ps PullRequestsByUpdatedAt) Swap(i, j int)      { ps[i], ps[j] = ps[j], ps[i] }
+func (ps PullRequestsByUpdatedAt) Less(i, j int) bool { return ps[i].UpdatedAt.Before(ps[j].UpdatedAt) }
+
+// PullRequestsByWaitingSince provides a sorter based on WaitingSince timestamp,
+// falling back to CreatedAt, from longest to shortest waiting.
+type PullRequestsByWaitingSince []PullRequest
+
+func (ps PullRequestsByWaitingSince) Len() int      { return len(ps) }
+func (ps PullRequestsByWaitingSince) Swap(i, j int) { ps[i], ps[j] = ps[j], ps[i] }
+func (ps PullRequestsByWaitingSince) Less(i, j int) bool {
+	return ps[i].waitingSince().Before(ps[j].waitingSince())
+}
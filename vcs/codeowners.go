@@ -0,0 +1,126 @@
+package vcs
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// codeOwners is a parsed CODEOWNERS file, following GitLab's syntax. Rules are
+// grouped by section; within a section the last matching rule wins, and the
+// owners of all sections are combined.
+type codeOwners struct {
+	sections [][]codeOwnersRule
+}
+
+type codeOwnersRule struct {
+	pattern *regexp.Regexp
+	owners  []string
+}
+
+func parseCodeOwners(s string) (*codeOwners, error) {
+	co := codeOwners{sections: [][]codeOwnersRule{nil}}
+	var defaultOwners []string
+
+	sc := bufio.NewScanner(strings.NewReader(s))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Section headers look like "[Section]", "^[Section]" or "[Section][2]",
+		// optionally followed by the section's default owners.
+		if strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			end := strings.LastIndex(line, "]")
+			if end == -1 {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			co.sections = append(co.sections, nil)
+			defaultOwners = strings.Fields(line[end+1:])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		owners := fields[1:]
+		if len(owners) == 0 {
+			owners = defaultOwners
+		}
+		re, err := compileCodeOwnersPattern(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s", lineNo, err)
+		}
+		i := len(co.sections) - 1
+		co.sections[i] = append(co.sections[i], codeOwnersRule{pattern: re, owners: owners})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("bufio: Scanner.Scan: %s", err)
+	}
+	return &co, nil
+}
+
+// owners returns the owners of path, as they are listed in the file.
+func (co *codeOwners) owners(path string) []string {
+	path = strings.TrimPrefix(path, "/")
+
+	var owners []string
+	for _, rules := range co.sections {
+		for i := len(rules) - 1; i >= 0; i-- {
+			if rules[i].pattern.MatchString(path) {
+				owners = append(owners, rules[i].owners...)
+				break
+			}
+		}
+	}
+	return owners
+}
+
+// compileCodeOwnersPattern compiles a gitignore style pattern into a regular
+// expression matching the paths it applies to, including all files within
+// matching directories.
+func compileCodeOwnersPattern(p string) (*regexp.Regexp, error) {
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	// Patterns containing a slash other than a trailing one are relative to
+	// the root of the repository, while others match at any depth.
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case p[i] == '*':
+			b.WriteString("[^/]*")
+		case p[i] == '?':
+			b.WriteString("[^/]")
+		case p[i] == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else {
+		b.WriteString("(?:/.*)?$")
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("regexp: Compile: %s", err)
+	}
+	return re, nil
+}
@@ -0,0 +1,467 @@
+package vcs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opencensus.io/plugin/ochttp"
+
+	"github.com/epels/preport"
+)
+
+type GitHub struct {
+	httpc           *http.Client
+	baseURL, bearer string
+
+	// MaxPages is the hard cap on pages used by ListPullRequests. It defaults
+	// to DefaultMaxPages.
+	MaxPages int
+	// WithApprovals makes ListPullRequests fetch the approval status of every
+	// pull request, at the cost of an additional request for each.
+	WithApprovals bool
+}
+
+var (
+	_ preport.PullRequestLister = (*GitHub)(nil)
+	_ preport.EmailLookup       = (*GitHub)(nil)
+)
+
+type pullsResponse []pullResponse
+
+type pullResponse struct {
+	Number    int
+	Title     string
+	HTMLURL   string `json:"html_url"`
+	User      loginResponse
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	Body      string
+	Draft     bool
+	Labels    []struct {
+		Name string
+	}
+	Milestone *struct {
+		Title string
+	}
+	Head struct {
+		Ref string
+	}
+	Base struct {
+		Ref  string
+		Repo *struct {
+			Name    string
+			HTMLURL string `json:"html_url"`
+		}
+	}
+	Assignees          []loginResponse
+	RequestedReviewers []loginResponse `json:"requested_reviewers"`
+	RequestedTeams     []struct {
+		Slug    string
+		Name    string
+		HTMLURL string `json:"html_url"`
+	} `json:"requested_teams"`
+}
+
+// project returns the repository of the pull request, which is derived from
+// repo and its URL when the response does not include it.
+func (r pullResponse) project(repo string) preport.Project {
+	p := preport.Project{
+		Path: repo,
+		Name: repo[strings.LastIndex(repo, "/")+1:],
+	}
+	if r.Base.Repo != nil {
+		p.Name, p.WebURL = r.Base.Repo.Name, r.Base.Repo.HTMLURL
+	} else if i := strings.LastIndex(r.HTMLURL, "/pull/"); i != -1 {
+		p.WebURL = r.HTMLURL[:i]
+	}
+	return p
+}
+
+type loginResponse struct {
+	Login     string
+	AvatarURL string `json:"avatar_url"`
+	HTMLURL   string `json:"html_url"`
+}
+
+type reviewsResponse []struct {
+	User  loginResponse
+	State string
+}
+
+// GitHubOptions are parameters used to filter the pull requests; values with
+// the respective type's zero values are discarded. Only open pull requests are
+// listed. As the GitHub API only supports filtering on the base branch, all
+// other filters are applied after fetching.
+type GitHubOptions struct {
+	IsDraft         *bool
+	HasAssignee     *bool
+	HasBeenApproved *bool
+	HasReviewer     *bool
+	Sort            Sort
+	// Labels only matches pull requests with all of the labels, and NotLabels
+	// those with none of them.
+	Labels    []string
+	NotLabels []string
+	// BaseBranch and HeadBranch are GitHub's terms for the target and source
+	// branch respectively.
+	BaseBranch string
+	HeadBranch string
+	// Milestone matches the title of the milestone.
+	Milestone   string
+	AuthorLogin string
+	// Search matches pull requests by their title and body, ignoring case.
+	Search  string
+	PerPage int
+	// WithApprovals fetches the approval status of every pull request, at the
+	// cost of an additional request for each. It is implied when filtering on
+	// HasBeenApproved, and for pull requests without requested reviewers when
+	// filtering on HasReviewer.
+	WithApprovals bool
+	// MaxPages is a hard cap on the number of pages fetched, after which any
+	// remaining pull requests are discarded. It defaults to DefaultMaxPages.
+	MaxPages int
+}
+
+func NewGitHub(baseURL, bearer string) (*GitHub, error) {
+	switch "" {
+	case baseURL:
+		return nil, errors.New("baseURL must not be empty")
+	case bearer:
+		return nil, errors.New("bearer must not be empty")
+	}
+	if u, err := url.Parse(baseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, errors.New("baseURL must be a valid http(s) URL")
+	}
+
+	return &GitHub{
+		httpc: &http.Client{
+			Transport: &ochttp.Transport{},
+			// Timeout is a generous duration intended as a fallback for when
+			// the caller does not provide a context with a sensible deadline.
+			Timeout: 30 * time.Second,
+		},
+		baseURL: baseURL,
+		bearer:  bearer,
+	}, nil
+}
+
+// ListPullRequests lists the open pull requests of repo, which is expected in
+// the "owner/name" format, from oldest to newest.
+func (g *GitHub) ListPullRequests(ctx context.Context, repo string, f preport.Filter) ([]preport.PullRequest, error) {
+	return g.ListPulls(ctx, repo, GitHubOptions{
+		IsDraft:         f.IsDraft,
+		HasAssignee:     f.HasAssignee,
+		HasBeenApproved: f.HasBeenApproved,
+		HasReviewer:     f.HasReviewer,
+		Sort:            SortAsc,
+		Labels:          f.Labels,
+		NotLabels:       f.NotLabels,
+		BaseBranch:      f.TargetBranch,
+		HeadBranch:      f.SourceBranch,
+		Milestone:       f.Milestone,
+		AuthorLogin:     f.Author,
+		Search:          f.Search,
+		MaxPages:        g.MaxPages,
+		WithApprovals:   g.WithApprovals,
+	})
+}
+
+// ListPulls lists the open pull requests of repo, which is expected in the
+// "owner/name" format.
+func (g *GitHub) ListPulls(ctx context.Context, repo string, opts GitHubOptions) ([]preport.PullRequest, error) {
+	if parts := strings.Split(repo, "/"); len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("repo must be formatted as owner/name, got: %q", repo)
+	}
+	vals, err := opts.toValues()
+	if err != nil {
+		return nil, fmt.Errorf("unable to validate GitHubOptions: %s", err)
+	}
+	maxPages := DefaultMaxPages
+	if opts.MaxPages != 0 {
+		maxPages = opts.MaxPages
+	}
+
+	var rs pullsResponse
+	u := fmt.Sprintf("%s/repos/%s/pulls?%s", g.baseURL, repo, vals.Encode())
+	for page := 1; u != ""; page++ {
+		if page > maxPages {
+			log.Printf("Reached limit of %d pages for repo %s; discarding remaining pull requests", maxPages, repo)
+			break
+		}
+
+		var pageRs pullsResponse
+		next, err := g.get(ctx, u, &pageRs)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, pageRs...)
+		u = next
+	}
+
+	prs := make([]preport.PullRequest, 0, len(rs))
+	for _, r := range rs {
+		if !opts.matches(r) {
+			continue
+		}
+		pr := r.toPullRequest()
+		pr.Project = r.project(repo)
+		// GitHub no longer lists reviewers as requested once they submitted a
+		// review, so whether there is a reviewer is only known from the reviews
+		// when none is requested.
+		requested := len(pr.Reviewers) > 0 || len(pr.ReviewerTeams) > 0
+		if opts.HasBeenApproved != nil || opts.WithApprovals || (opts.HasReviewer != nil && !requested) {
+			var reviewers []preport.Author
+			if pr.Approval, reviewers, err = g.reviews(ctx, repo, r.Number, r.User.Login); err != nil {
+				return nil, err
+			}
+			pr.Reviewers = addReviewers(pr.Reviewers, reviewers)
+			if !matches(opts.HasBeenApproved, pr.Approval.Approved) {
+				continue
+			}
+		}
+		if !matches(opts.HasReviewer, len(pr.Reviewers) > 0 || len(pr.ReviewerTeams) > 0) {
+			continue
+		}
+		prs = append(prs, pr)
+	}
+	return prs, nil
+}
+
+// reviews derives the approval status of the pull request from the latest
+// review of every reviewer, and returns the users other than author who
+// submitted a review. As GitHub does not expose the number of required
+// approvals, it is approved as soon as any reviewer approved it.
+func (g *GitHub) reviews(ctx context.Context, repo string, number int, author string) (preport.Approval, []preport.Author, error) {
+	var reviewers []preport.Author
+	latest := make(map[string]string)
+	u := fmt.Sprintf("%s/repos/%s/pulls/%d/reviews?per_page=100", g.baseURL, repo, number)
+	for u != "" {
+		var rs reviewsResponse
+		next, err := g.get(ctx, u, &rs)
+		if err != nil {
+			return preport.Approval{}, nil, err
+		}
+		for _, r := range rs {
+			// Pending reviews have not been submitted yet.
+			if r.State == "PENDING" || r.User.Login == author {
+				continue
+			}
+			if _, ok := latest[r.User.Login]; !ok {
+				reviewers = append(reviewers, r.User.toAuthor())
+				latest[r.User.Login] = ""
+			}
+			// Comments do not change the verdict of an earlier review.
+			if r.State != "COMMENTED" {
+				latest[r.User.Login] = r.State
+			}
+		}
+		u = next
+	}
+
+	var a preport.Approval
+	for _, r := range reviewers {
+		if latest[r.Username] == "APPROVED" {
+			a.ApprovedBy = append(a.ApprovedBy, preport.Author{Username: r.Username})
+		}
+	}
+	a.Approved = len(a.ApprovedBy) > 0
+	return a, reviewers, nil
+}
+
+// addReviewers adds the reviewers in rs to those in as, skipping users that are
+// already in as.
+func addReviewers(as, rs []preport.Author) []preport.Author {
+	for _, r := range rs {
+		found := false
+		for _, a := range as {
+			if a.Username == r.Username {
+				found = true
+				break
+			}
+		}
+		if !found {
+			as = append(as, r)
+		}
+	}
+	return as
+}
+
+// LookupEmail looks up the public email address of the user with login.
+func (g *GitHub) LookupEmail(ctx context.Context, login string) (string, error) {
+	var user struct {
+		Email string
+	}
+	if _, err := g.get(ctx, fmt.Sprintf("%s/users/%s", g.baseURL, url.PathEscape(login)), &user); err != nil {
+		return "", err
+	}
+	return user.Email, nil
+}
+
+// get performs a GET request to u and decodes the JSON response body into v.
+// It returns the URL of the next page, or an empty string when u points to the
+// last page.
+func (g *GitHub) get(ctx context.Context, u string, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return "", fmt.Errorf("net/http: NewRequestWithContext: %s", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.bearer)
+
+	res, err := g.httpc.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("net/http: Client.Do: %s", err)
+	}
+	defer func() {
+		if err := res.Body.Close(); err != nil {
+			log.Printf("%T: Close: %s", res.Body, err)
+		}
+	}()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code: %d", res.StatusCode)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("encoding/json: Decoder.Decode: %s", err)
+	}
+	return nextPageURL(req.URL, res.Header)
+}
+
+func (r pullResponse) toPullRequest() preport.PullRequest {
+	var labels []string
+	for _, l := range r.Labels {
+		labels = append(labels, l.Name)
+	}
+	var teams []preport.Team
+	for _, t := range r.RequestedTeams {
+		teams = append(teams, preport.Team{Slug: t.Slug, Name: t.Name, WebURL: t.HTMLURL})
+	}
+
+	return preport.PullRequest{
+		Title:         r.Title,
+		URL:           r.HTMLURL,
+		Author:        r.User.toAuthor(),
+		CreatedAt:     r.CreatedAt,
+		IID:           r.Number,
+		Description:   r.Body,
+		Labels:        labels,
+		Draft:         r.Draft,
+		Assignees:     loginsToAuthors(r.Assignees),
+		Reviewers:     loginsToAuthors(r.RequestedReviewers),
+		ReviewerTeams: teams,
+		SourceBranch:  r.Head.Ref,
+		TargetBranch:  r.Base.Ref,
+		UpdatedAt:     r.UpdatedAt,
+	}
+}
+
+// toAuthor maps r to an Author. As GitHub only includes the name of users in
+// their own resource, it is left empty.
+func (r loginResponse) toAuthor() preport.Author {
+	return preport.Author{
+		Username:  r.Login,
+		AvatarURL: r.AvatarURL,
+		WebURL:    r.HTMLURL,
+	}
+}
+
+func loginsToAuthors(rs []loginResponse) []preport.Author {
+	if len(rs) == 0 {
+		return nil
+	}
+	as := make([]preport.Author, 0, len(rs))
+	for _, r := range rs {
+		as = append(as, r.toAuthor())
+	}
+	return as
+}
+
+// matches reports whether r satisfies all options that can be verified without
+// additional requests.
+func (o GitHubOptions) matches(r pullResponse) bool {
+	if !matches(o.IsDraft, r.Draft) || !matches(o.HasAssignee, len(r.Assignees) > 0) {
+		return false
+	}
+	// Pull requests with requested reviewers have a reviewer regardless of
+	// their reviews.
+	if o.HasReviewer != nil && !*o.HasReviewer && (len(r.RequestedReviewers) > 0 || len(r.RequestedTeams) > 0) {
+		return false
+	}
+
+	labels := make(map[string]bool, len(r.Labels))
+	for _, l := range r.Labels {
+		labels[l.Name] = true
+	}
+	for _, l := range o.Labels {
+		if !labels[l] {
+			return false
+		}
+	}
+	for _, l := range o.NotLabels {
+		if labels[l] {
+			return false
+		}
+	}
+
+	if o.HeadBranch != "" && r.Head.Ref != o.HeadBranch {
+		return false
+	}
+	if o.Milestone != "" && (r.Milestone == nil || r.Milestone.Title != o.Milestone) {
+		return false
+	}
+	if o.AuthorLogin != "" && !strings.EqualFold(r.User.Login, o.AuthorLogin) {
+		return false
+	}
+	if o.Search != "" {
+		search := strings.ToLower(o.Search)
+		if !strings.Contains(strings.ToLower(r.Title), search) && !strings.Contains(strings.ToLower(r.Body), search) {
+			return false
+		}
+	}
+	return true
+}
+
+func (o GitHubOptions) validate() error {
+	switch o.Sort {
+	case "", SortAsc, SortDesc:
+	default:
+		return fmt.Errorf("unexpected sort: %q", o.Sort)
+	}
+
+	return nil
+}
+
+func (o GitHubOptions) toValues() (url.Values, error) {
+	if err := o.validate(); err != nil {
+		return nil, err
+	}
+
+	v := url.Values{}
+	v.Set("state", "open")
+	if o.BaseBranch != "" {
+		v.Set("base", o.BaseBranch)
+	}
+	if o.Sort != "" {
+		v.Set("sort", "created")
+		v.Set("direction", string(o.Sort))
+	}
+	perPage := 100
+	if o.PerPage != 0 {
+		perPage = o.PerPage
+	}
+	v.Set("per_page", strconv.Itoa(perPage))
+	return v, nil
+}
+
+// matches reports whether actual satisfies the optional filter want.
+func matches(want *bool, actual bool) bool {
+	return want == nil || *want == actual
+}
@@ -0,0 +1,305 @@
+package vcs_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/epels/preport"
+	"github.com/epels/preport/internal/testutil"
+	"github.com/epels/preport/vcs"
+)
+
+func TestNewGitHub(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		gc, err := vcs.NewGitHub("https://example.com", "bearer")
+		require.NoError(t, err)
+		assert.NotNil(t, gc)
+	})
+	t.Run("Invalid baseURL", func(t *testing.T) {
+		_, err := vcs.NewGitHub("ftp://example.com", "bearer")
+		require.Error(t, err)
+	})
+	t.Run("Empty baseURL", func(t *testing.T) {
+		_, err := vcs.NewGitHub("", "bearer")
+		require.Error(t, err)
+	})
+	t.Run("Empty bearer", func(t *testing.T) {
+		_, err := vcs.NewGitHub("https://example.com", "")
+		require.Error(t, err)
+	})
+}
+
+func TestGitHub_ListPulls(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "Bearer super-secret", r.Header.Get("Authorization"))
+
+			switch r.URL.Path {
+			case "/repos/octo-org/hello-world/pulls":
+				assert.Equal(t, url.Values{
+					"state":     []string{"open"},
+					"sort":      []string{"created"},
+					"direction": []string{"asc"},
+					"per_page":  []string{"100"},
+				}, r.URL.Query())
+				testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+			case "/repos/octo-org/hello-world/pulls/7/reviews":
+				testutil.WriteTestdata(t, "testdata/github_reviews_changes_requested_response.json", w)
+			case "/repos/octo-org/hello-world/pulls/11/reviews":
+				testutil.WriteTestdata(t, "testdata/github_reviews_approved_response.json", w)
+			default:
+				t.Errorf("Unexpected call to %q", r.URL.Path)
+			}
+		})
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{
+			Sort:            vcs.SortAsc,
+			IsDraft:         boolPointer(t, false),
+			HasAssignee:     boolPointer(t, false),
+			HasReviewer:     boolPointer(t, false),
+			HasBeenApproved: boolPointer(t, false),
+		})
+		require.NoError(t, err)
+		// Pull request 7 has a reviewer who requested changes, and is no
+		// longer listed as requested, whereas 11 has been approved.
+		assert.Empty(t, prs)
+	})
+
+	t.Run("With approvals", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/octo-org/hello-world/pulls":
+				testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+			case "/repos/octo-org/hello-world/pulls/11/reviews":
+				testutil.WriteTestdata(t, "testdata/github_reviews_approved_response.json", w)
+			default:
+				testutil.WriteTestdata(t, "testdata/github_reviews_changes_requested_response.json", w)
+			}
+		})
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{
+			WithApprovals: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 6)
+		assert.Equal(t, preport.Approval{}, prs[0].Approval)
+		assert.Equal(t, preport.Approval{
+			Approved: true,
+			ApprovedBy: []preport.Author{
+				{Username: "octocat"},
+			},
+		}, prs[4].Approval)
+	})
+
+	t.Run("Without filters", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/repos/octo-org/hello-world/pulls", r.URL.Path)
+			testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+		})
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{})
+		require.NoError(t, err)
+		require.Len(t, prs, 6)
+		assert.Equal(t, preport.PullRequest{
+			Title: "Add search",
+			URL:   "https://github.com/octo-org/hello-world/pull/7",
+			Author: preport.Author{
+				Username: "octocat",
+				WebURL:   "https://github.com/octocat",
+			},
+			CreatedAt: mustParseRFC3339(t, "2021-04-01T09:00:00Z"),
+			Project: preport.Project{
+				Path:   "octo-org/hello-world",
+				Name:   "hello-world",
+				WebURL: "https://github.com/octo-org/hello-world",
+			},
+			IID:          7,
+			Description:  "Adds full-text search to the index page.",
+			Labels:       []string{"backend"},
+			SourceBranch: "feature-7",
+			TargetBranch: "main",
+			UpdatedAt:    mustParseRFC3339(t, "2021-04-01T09:00:00Z"),
+		}, prs[0])
+		assert.Empty(t, prs[3].Reviewers)
+		assert.Equal(t, []preport.Team{
+			{
+				Slug:   "justice-league",
+				Name:   "Justice League",
+				WebURL: "https://github.com/orgs/octo-org/teams/justice-league",
+			},
+		}, prs[3].ReviewerTeams)
+	})
+
+	t.Run("Submitted reviews", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/repos/octo-org/hello-world/pulls":
+				testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+			case "/repos/octo-org/hello-world/pulls/7/reviews":
+				testutil.WriteTestdata(t, "testdata/github_reviews_changes_requested_response.json", w)
+			case "/repos/octo-org/hello-world/pulls/11/reviews", "/repos/octo-org/hello-world/pulls/12/reviews":
+				_, _ = w.Write([]byte("[]"))
+			default:
+				t.Errorf("Unexpected call to %q", r.URL.Path)
+			}
+		})
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{
+			IsDraft:     boolPointer(t, false),
+			HasReviewer: boolPointer(t, true),
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 3)
+		assert.Equal(t, 7, prs[0].IID)
+		assert.Equal(t, []preport.Author{
+			{Username: "monalisa", WebURL: "https://github.com/monalisa"},
+			{Username: "hubot", WebURL: "https://github.com/hubot"},
+		}, prs[0].Reviewers)
+		assert.Equal(t, preport.Approval{}, prs[0].Approval)
+		assert.Equal(t, 9, prs[1].IID)
+		assert.Equal(t, 10, prs[2].IID)
+
+		prs, err = gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{
+			IsDraft:     boolPointer(t, false),
+			HasReviewer: boolPointer(t, false),
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, 11, prs[0].IID)
+		assert.Equal(t, 12, prs[1].IID)
+	})
+
+	t.Run("Client-side filters", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/repos/octo-org/hello-world/pulls", r.URL.Path)
+			assert.Equal(t, "main", r.URL.Query().Get("base"))
+			testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+		})
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{
+			Labels:      []string{"backend"},
+			NotLabels:   []string{"do-not-review"},
+			BaseBranch:  "main",
+			HeadBranch:  "feature-7",
+			Milestone:   "v1.0",
+			AuthorLogin: "OctoCat",
+			Search:      "FULL-TEXT",
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 1)
+		assert.Equal(t, "Add search", prs[0].Title)
+
+		prs, err = gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{
+			BaseBranch: "main",
+			NotLabels:  []string{"do-not-review", "backend"},
+		})
+		require.NoError(t, err)
+		assert.Len(t, prs, 3)
+	})
+
+	t.Run("Multiple pages", func(t *testing.T) {
+		var serverURL string
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("page") {
+			case "":
+				w.Header().Set("Link", fmt.Sprintf(`<%s/repos/octo-org/hello-world/pulls?page=2>; rel="next", <%s/repos/octo-org/hello-world/pulls?page=2>; rel="last"`, serverURL, serverURL))
+			case "2":
+			default:
+				t.Errorf("Unexpected page %q", r.URL.Query().Get("page"))
+			}
+			testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+		})
+		serverURL = ts.URL
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{})
+		require.NoError(t, err)
+		assert.Len(t, prs, 12)
+	})
+
+	t.Run("Invalid repo", func(t *testing.T) {
+		gc, err := vcs.NewGitHub("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		_, err = gc.ListPulls(context.Background(), "hello-world", vcs.GitHubOptions{})
+		require.Error(t, err)
+	})
+
+	t.Run("Internal error", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		_, err = gc.ListPulls(context.Background(), "octo-org/hello-world", vcs.GitHubOptions{})
+		require.Error(t, err)
+	})
+}
+
+func TestGitHub_ListPullRequests(t *testing.T) {
+	ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/octo-org/hello-world/pulls" {
+			// None of the pull requests without requested reviewers has been
+			// reviewed.
+			assert.True(t, strings.HasSuffix(r.URL.Path, "/reviews"))
+			_, _ = w.Write([]byte("[]"))
+			return
+		}
+		assert.Equal(t, "asc", r.URL.Query().Get("direction"))
+
+		testutil.WriteTestdata(t, "testdata/github_ok_response.json", w)
+	})
+
+	gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+	require.NoError(t, err)
+
+	prs, err := gc.ListPullRequests(context.Background(), "octo-org/hello-world", preport.Filter{
+		IsDraft:     boolPointer(t, false),
+		HasReviewer: boolPointer(t, true),
+	})
+	require.NoError(t, err)
+	require.Len(t, prs, 2)
+	assert.Equal(t, "Has reviewer", prs[0].Title)
+	assert.Equal(t, "Has team reviewer", prs[1].Title)
+}
+
+func TestGitHub_LookupEmail(t *testing.T) {
+	ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/users/octocat", r.URL.Path)
+		_, _ = w.Write([]byte(`{"login": "octocat", "email": "octocat@github.com"}`))
+	})
+
+	gc, err := vcs.NewGitHub(ts.URL, "super-secret")
+	require.NoError(t, err)
+
+	email, err := gc.LookupEmail(context.Background(), "octocat")
+	require.NoError(t, err)
+	assert.Equal(t, "octocat@github.com", email)
+}
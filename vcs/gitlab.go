@@ -5,10 +5,13 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"go.opencensus.io/plugin/ochttp"
@@ -19,17 +22,73 @@ import (
 type Gitlab struct {
 	httpc           *http.Client
 	baseURL, bearer string
+
+	// MaxPages is the hard cap on pages used by ListPullRequests. It defaults
+	// to DefaultMaxPages.
+	MaxPages int
+	// WithApprovals makes ListPullRequests fetch the approval status of every
+	// merge request, at the cost of an additional request for each.
+	WithApprovals bool
+	// WithWaitingSince makes ListPullRequests derive when every merge request
+	// started waiting on a reviewer from its notes, at the cost of an
+	// additional request for each.
+	WithWaitingSince bool
+
+	mu sync.Mutex
+	// codeOwners caches the parsed CODEOWNERS files by project and branch; a
+	// nil value means the project has none.
+	codeOwners map[string]*codeOwners
 }
 
+var (
+	_ preport.PullRequestLister = (*Gitlab)(nil)
+	_ preport.GroupLister       = (*Gitlab)(nil)
+	_ preport.EmailLookup       = (*Gitlab)(nil)
+	_ preport.OwnerSuggester    = (*Gitlab)(nil)
+	_ preport.ReviewerAssigner  = (*Gitlab)(nil)
+)
+
+// codeOwnersPaths are the locations of the CODEOWNERS file, in the order in
+// which GitLab looks for it.
+var codeOwnersPaths = []string{"CODEOWNERS", "docs/CODEOWNERS", ".gitlab/CODEOWNERS"}
+
 type mergeRequestsResponse []mergeRequestResponse
 
 type mergeRequestResponse struct {
-	Title  string
-	WebURL string `json:"web_url"`
-	Author struct {
-		Username string
+	IID        int
+	ProjectID  int `json:"project_id"`
+	Title      string
+	WebURL     string `json:"web_url"`
+	References struct {
+		Full string
 	}
-	CreatedAt time.Time `json:"created_at"`
+	Author         userResponse
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Description    string
+	Labels         []string
+	Draft          bool
+	WorkInProgress bool `json:"work_in_progress"`
+	Assignees      []userResponse
+	Reviewers      []userResponse
+	SourceBranch   string `json:"source_branch"`
+	TargetBranch   string `json:"target_branch"`
+	UserNotesCount int    `json:"user_notes_count"`
+}
+
+type userResponse struct {
+	Username  string
+	Name      string
+	AvatarURL string `json:"avatar_url"`
+	WebURL    string `json:"web_url"`
+}
+
+type approvalsResponse struct {
+	Approved      bool
+	ApprovalsLeft int `json:"approvals_left"`
+	ApprovedBy    []struct {
+		User userResponse
+	} `json:"approved_by"`
 }
 
 type (
@@ -48,7 +107,26 @@ type GitlabOptions struct {
 	HasBeenApproved *bool
 	HasReviewer     *bool
 	Sort            Sort
-	PerPage         int
+	// Labels only matches pull requests with all of the labels, and NotLabels
+	// those with none of them.
+	Labels         []string
+	NotLabels      []string
+	TargetBranch   string
+	SourceBranch   string
+	Milestone      string
+	AuthorUsername string
+	// Search matches pull requests by their title and description.
+	Search  string
+	PerPage int
+	// MaxPages is a hard cap on the number of pages fetched, after which any
+	// remaining pull requests are discarded. It defaults to DefaultMaxPages.
+	MaxPages int
+	// WithApprovals fetches the approval status of every merge request, at the
+	// cost of an additional request for each.
+	WithApprovals bool
+	// WithWaitingSince derives when every merge request started waiting on a
+	// reviewer from its notes, at the cost of an additional request for each.
+	WithWaitingSince bool
 }
 
 const (
@@ -65,6 +143,10 @@ const (
 	StateOpened State = "opened"
 )
 
+// DefaultMaxPages is the number of pages fetched when GitlabOptions.MaxPages
+// is not set.
+const DefaultMaxPages = 10
+
 var False = false
 
 func NewGitlab(baseURL, bearer string) (*Gitlab, error) {
@@ -90,14 +172,372 @@ func NewGitlab(baseURL, bearer string) (*Gitlab, error) {
 	}, nil
 }
 
-func (g *Gitlab) ListPullRequests(ctx context.Context, projectID string, opts GitlabOptions) ([]preport.PullRequest, error) {
+// ListPullRequests lists the open merge requests of the project, from oldest
+// to newest.
+func (g *Gitlab) ListPullRequests(ctx context.Context, projectID string, f preport.Filter) ([]preport.PullRequest, error) {
+	return g.ListMergeRequests(ctx, projectID, g.filterOptions(f))
+}
+
+// ListGroupPullRequests lists the open merge requests of all projects in the
+// group, including those in its subgroups, from oldest to newest.
+func (g *Gitlab) ListGroupPullRequests(ctx context.Context, groupID string, f preport.Filter) ([]preport.PullRequest, error) {
+	return g.ListGroupMergeRequests(ctx, groupID, g.filterOptions(f))
+}
+
+func (g *Gitlab) filterOptions(f preport.Filter) GitlabOptions {
+	return GitlabOptions{
+		Scope:           ScopeAll,
+		State:           StateOpened,
+		IsDraft:         f.IsDraft,
+		HasAssignee:     f.HasAssignee,
+		HasBeenApproved: f.HasBeenApproved,
+		HasReviewer:     f.HasReviewer,
+		Sort:            SortAsc,
+		Labels:          f.Labels,
+		NotLabels:       f.NotLabels,
+		TargetBranch:    f.TargetBranch,
+		SourceBranch:    f.SourceBranch,
+		Milestone:       f.Milestone,
+		AuthorUsername:  f.Author,
+		Search:          f.Search,
+		MaxPages:        g.MaxPages,
+		WithApprovals:   g.WithApprovals,
+
+		WithWaitingSince: g.WithWaitingSince,
+	}
+}
+
+func (g *Gitlab) ListMergeRequests(ctx context.Context, projectID string, opts GitlabOptions) ([]preport.PullRequest, error) {
+	return g.listMergeRequests(ctx, "projects/"+projectID, opts)
+}
+
+// ListGroupMergeRequests lists the merge requests of all projects in the
+// group, including those in its subgroups. The group is referred to by its ID
+// or full path, e.g. "parent/child".
+func (g *Gitlab) ListGroupMergeRequests(ctx context.Context, groupID string, opts GitlabOptions) ([]preport.PullRequest, error) {
+	return g.listMergeRequests(ctx, "groups/"+url.PathEscape(groupID), opts)
+}
+
+// listMergeRequests lists the merge requests of the resource at path, which is
+// either a project or a group.
+func (g *Gitlab) listMergeRequests(ctx context.Context, path string, opts GitlabOptions) ([]preport.PullRequest, error) {
 	vals, err := opts.toValues()
 	if err != nil {
 		return nil, fmt.Errorf("unable to validate GitlabOptions: %s", err)
 	}
+	maxPages := DefaultMaxPages
+	if opts.MaxPages != 0 {
+		maxPages = opts.MaxPages
+	}
+
+	var rs mergeRequestsResponse
+	u := fmt.Sprintf("%s/api/v4/%s/merge_requests?%s", g.baseURL, path, vals.Encode())
+	for page := 1; u != ""; page++ {
+		if page > maxPages {
+			log.Printf("Reached limit of %d pages for %s; discarding remaining pull requests", maxPages, path)
+			break
+		}
+
+		var pageRs mergeRequestsResponse
+		next, err := g.get(ctx, u, &pageRs)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, pageRs...)
+		u = next
+	}
+
+	prs := rs.toPullRequests()
+	for i, r := range rs {
+		if opts.WithApprovals {
+			if prs[i].Approval, err = g.approval(ctx, r.ProjectID, r.IID); err != nil {
+				return nil, err
+			}
+		}
+		if opts.WithWaitingSince {
+			if prs[i].WaitingSince, err = g.waitingSince(ctx, r, maxPages); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return prs, nil
+}
+
+// waitingSince derives when the merge request started waiting on a reviewer:
+// the latest of its creation, its last note by the author, including pushes,
+// and the last time a review was requested. Notes are paged through from
+// newest to oldest until a matching one is found, up to maxPages pages.
+func (g *Gitlab) waitingSince(ctx context.Context, r mergeRequestResponse, maxPages int) (time.Time, error) {
+	vals := url.Values{"sort": {"desc"}, "order_by": {"created_at"}, "per_page": {"100"}}
+	u := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/notes?%s", g.baseURL, r.ProjectID, r.IID, vals.Encode())
+	for page := 1; u != "" && page <= maxPages; page++ {
+		var notes []struct {
+			Body      string
+			Author    userResponse
+			CreatedAt time.Time `json:"created_at"`
+			System    bool
+		}
+		next, err := g.get(ctx, u, &notes)
+		if err != nil {
+			return time.Time{}, err
+		}
+
+		for _, n := range notes {
+			if n.Author.Username != r.Author.Username && !(n.System && strings.HasPrefix(n.Body, "requested review from")) {
+				continue
+			}
+			if n.CreatedAt.After(r.CreatedAt) {
+				return n.CreatedAt, nil
+			}
+			return r.CreatedAt, nil
+		}
+		u = next
+	}
+	return r.CreatedAt, nil
+}
+
+func (g *Gitlab) approval(ctx context.Context, projectID, iid int) (preport.Approval, error) {
+	var r approvalsResponse
+	u := fmt.Sprintf("%s/api/v4/projects/%d/merge_requests/%d/approvals", g.baseURL, projectID, iid)
+	if _, err := g.get(ctx, u, &r); err != nil {
+		return preport.Approval{}, err
+	}
+
+	a := preport.Approval{
+		Approved: r.Approved,
+		Left:     r.ApprovalsLeft,
+	}
+	for _, ab := range r.ApprovedBy {
+		a.ApprovedBy = append(a.ApprovedBy, ab.User.toAuthor())
+	}
+	return a, nil
+}
+
+// LookupEmail looks up the public email address of the user with username.
+func (g *Gitlab) LookupEmail(ctx context.Context, username string) (string, error) {
+	id, err := g.userID(ctx, username)
+	if err != nil {
+		return "", err
+	}
+
+	// Only the endpoint for a single user exposes the public email address.
+	var user struct {
+		PublicEmail string `json:"public_email"`
+	}
+	if _, err := g.get(ctx, fmt.Sprintf("%s/api/v4/users/%d", g.baseURL, id), &user); err != nil {
+		return "", err
+	}
+	return user.PublicEmail, nil
+}
+
+// AssignReviewer sets the user with username as the only reviewer of the
+// merge request.
+func (g *Gitlab) AssignReviewer(ctx context.Context, pr preport.PullRequest, username string) error {
+	id, err := g.userID(ctx, username)
+	if err != nil {
+		return err
+	}
 
-	u := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests?%s", g.baseURL, projectID, vals.Encode())
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	vals := url.Values{"reviewer_ids[]": {strconv.Itoa(id)}}
+	u := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d?%s",
+		g.baseURL, url.PathEscape(pr.Project.Path), pr.IID, vals.Encode())
+	res, err := g.do(ctx, http.MethodPut, u)
+	if err != nil {
+		return err
+	}
+	closeBody(res)
+	return nil
+}
+
+// CountReviews counts the open merge requests the user with username is a
+// reviewer of, across all projects visible to the client.
+func (g *Gitlab) CountReviews(ctx context.Context, username string) (int, error) {
+	vals := url.Values{
+		"scope":             {string(ScopeAll)},
+		"state":             {string(StateOpened)},
+		"reviewer_username": {username},
+		"per_page":          {"100"},
+	}
+	u := fmt.Sprintf("%s/api/v4/merge_requests?%s", g.baseURL, vals.Encode())
+	res, err := g.do(ctx, http.MethodGet, u)
+	if err != nil {
+		return 0, err
+	}
+	defer closeBody(res)
+
+	// The total is omitted for large result sets, in which case the first
+	// page is counted instead.
+	if total := res.Header.Get("X-Total"); total != "" {
+		n, err := strconv.Atoi(total)
+		if err != nil {
+			return 0, fmt.Errorf("strconv: Atoi: %s", err)
+		}
+		return n, nil
+	}
+	var rs []struct{}
+	if err := json.NewDecoder(res.Body).Decode(&rs); err != nil {
+		return 0, fmt.Errorf("encoding/json: Decoder.Decode: %s", err)
+	}
+	return len(rs), nil
+}
+
+// userID looks up the ID of the user with username.
+func (g *Gitlab) userID(ctx context.Context, username string) (int, error) {
+	var users []struct {
+		ID int
+	}
+	u := fmt.Sprintf("%s/api/v4/users?%s", g.baseURL, url.Values{"username": {username}}.Encode())
+	if _, err := g.get(ctx, u, &users); err != nil {
+		return 0, err
+	}
+	if len(users) == 0 {
+		return 0, fmt.Errorf("user not found: %q", username)
+	}
+	return users[0].ID, nil
+}
+
+// SuggestOwners suggests the owners of the files changed by the merge request,
+// based on the CODEOWNERS file on its target branch. Owners are returned in the
+// order in which they are first listed, excluding the author. Groups are
+// included by their full path, while email addresses and roles are omitted.
+func (g *Gitlab) SuggestOwners(ctx context.Context, pr preport.PullRequest) ([]preport.Author, error) {
+	projectID := url.PathEscape(pr.Project.Path)
+	co, err := g.loadCodeOwners(ctx, projectID, pr.TargetBranch)
+	if err != nil {
+		return nil, err
+	}
+	if co == nil {
+		return nil, nil
+	}
+
+	paths, err := g.changedPaths(ctx, projectID, pr.IID)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{pr.Author.Username: true}
+	var owners []preport.Author
+	for _, p := range paths {
+		for _, o := range co.owners(p) {
+			if !strings.HasPrefix(o, "@") || strings.HasPrefix(o, "@@") {
+				continue
+			}
+			username := strings.TrimPrefix(o, "@")
+			if seen[username] {
+				continue
+			}
+			seen[username] = true
+			owners = append(owners, preport.Author{Username: username})
+		}
+	}
+	return owners, nil
+}
+
+// loadCodeOwners returns the CODEOWNERS file of the project at ref, or nil
+// when it has none.
+func (g *Gitlab) loadCodeOwners(ctx context.Context, projectID, ref string) (*codeOwners, error) {
+	key := projectID + "@" + ref
+	g.mu.Lock()
+	co, ok := g.codeOwners[key]
+	g.mu.Unlock()
+	if ok {
+		return co, nil
+	}
+
+	for _, p := range codeOwnersPaths {
+		u := fmt.Sprintf("%s/api/v4/projects/%s/repository/files/%s/raw?%s",
+			g.baseURL, projectID, url.PathEscape(p), url.Values{"ref": {ref}}.Encode())
+		b, err := g.getRaw(ctx, u)
+		var sce statusCodeError
+		if errors.As(err, &sce) && sce == http.StatusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if co, err = parseCodeOwners(string(b)); err != nil {
+			return nil, fmt.Errorf("invalid %s in %s: %s", p, projectID, err)
+		}
+		break
+	}
+
+	g.mu.Lock()
+	if g.codeOwners == nil {
+		g.codeOwners = make(map[string]*codeOwners)
+	}
+	g.codeOwners[key] = co
+	g.mu.Unlock()
+	return co, nil
+}
+
+// changedPaths lists the paths changed by the merge request, including the
+// old paths of renamed files.
+func (g *Gitlab) changedPaths(ctx context.Context, projectID string, iid int) ([]string, error) {
+	var paths []string
+	u := fmt.Sprintf("%s/api/v4/projects/%s/merge_requests/%d/diffs?per_page=100", g.baseURL, projectID, iid)
+	for u != "" {
+		var diffs []struct {
+			OldPath string `json:"old_path"`
+			NewPath string `json:"new_path"`
+		}
+		next, err := g.get(ctx, u, &diffs)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range diffs {
+			paths = append(paths, d.NewPath)
+			if d.OldPath != d.NewPath {
+				paths = append(paths, d.OldPath)
+			}
+		}
+		u = next
+	}
+	return paths, nil
+}
+
+// statusCodeError is returned for responses with an unexpected status code.
+type statusCodeError int
+
+func (e statusCodeError) Error() string {
+	return fmt.Sprintf("unexpected status code: %d", int(e))
+}
+
+// get performs a GET request to u and decodes the JSON response body into v.
+// It returns the URL of the next page, or an empty string when u points to the
+// last page.
+func (g *Gitlab) get(ctx context.Context, u string, v interface{}) (string, error) {
+	res, err := g.do(ctx, http.MethodGet, u)
+	if err != nil {
+		return "", err
+	}
+	defer closeBody(res)
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("encoding/json: Decoder.Decode: %s", err)
+	}
+	return nextPageURL(res.Request.URL, res.Header)
+}
+
+// getRaw performs a GET request to u and returns the response body as is.
+func (g *Gitlab) getRaw(ctx context.Context, u string) ([]byte, error) {
+	res, err := g.do(ctx, http.MethodGet, u)
+	if err != nil {
+		return nil, err
+	}
+	defer closeBody(res)
+
+	b, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("io/ioutil: ReadAll: %s", err)
+	}
+	return b, nil
+}
+
+// do performs a request to u. The caller must close the body of the response,
+// which is only returned for status code 200.
+func (g *Gitlab) do(ctx context.Context, method, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, http.NoBody)
 	if err != nil {
 		return nil, fmt.Errorf("net/http: NewRequestWithContext: %s", err)
 	}
@@ -107,20 +547,17 @@ func (g *Gitlab) ListPullRequests(ctx context.Context, projectID string, opts Gi
 	if err != nil {
 		return nil, fmt.Errorf("net/http: Client.Do: %s", err)
 	}
-	defer func() {
-		if err := res.Body.Close(); err != nil {
-			log.Printf("%T: Close: %s", res.Body, err)
-		}
-	}()
 	if res.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", res.StatusCode)
+		closeBody(res)
+		return nil, statusCodeError(res.StatusCode)
 	}
+	return res, nil
+}
 
-	var rs mergeRequestsResponse
-	if err := json.NewDecoder(res.Body).Decode(&rs); err != nil {
-		return nil, fmt.Errorf("encoding/json: Decoder.Decode: %s", err)
+func closeBody(res *http.Response) {
+	if err := res.Body.Close(); err != nil {
+		log.Printf("%T: Close: %s", res.Body, err)
 	}
-	return rs.toPullRequests(), nil
 }
 
 func (rs mergeRequestsResponse) toPullRequests() []preport.PullRequest {
@@ -132,14 +569,58 @@ func (rs mergeRequestsResponse) toPullRequests() []preport.PullRequest {
 }
 
 func (r mergeRequestResponse) toPullRequest() preport.PullRequest {
+	// The full reference is formatted as "group/project!iid", and the URL as
+	// "https://gitlab.com/group/project/-/merge_requests/iid".
+	path := r.References.Full
+	if i := strings.LastIndex(path, "!"); i != -1 {
+		path = path[:i]
+	}
+	var webURL string
+	if i := strings.LastIndex(r.WebURL, "/-/merge_requests/"); i != -1 {
+		webURL = r.WebURL[:i]
+	}
+
 	return preport.PullRequest{
 		Title: r.Title,
 		URL:   r.WebURL,
-		Author: preport.Author{
-			Username: r.Author.Username,
+		Project: preport.Project{
+			Path:   path,
+			Name:   path[strings.LastIndex(path, "/")+1:],
+			WebURL: webURL,
 		},
-		CreatedAt: r.CreatedAt,
+		Author:       r.Author.toAuthor(),
+		CreatedAt:    r.CreatedAt,
+		IID:          r.IID,
+		Description:  r.Description,
+		Labels:       r.Labels,
+		Draft:        r.Draft || r.WorkInProgress,
+		Assignees:    toAuthors(r.Assignees),
+		Reviewers:    toAuthors(r.Reviewers),
+		SourceBranch: r.SourceBranch,
+		TargetBranch: r.TargetBranch,
+		UpdatedAt:    r.UpdatedAt,
+		Comments:     r.UserNotesCount,
+	}
+}
+
+func (r userResponse) toAuthor() preport.Author {
+	return preport.Author{
+		Username:  r.Username,
+		Name:      r.Name,
+		AvatarURL: r.AvatarURL,
+		WebURL:    r.WebURL,
+	}
+}
+
+func toAuthors(rs []userResponse) []preport.Author {
+	if len(rs) == 0 {
+		return nil
+	}
+	as := make([]preport.Author, 0, len(rs))
+	for _, r := range rs {
+		as = append(as, r.toAuthor())
 	}
+	return as
 }
 
 func (o GitlabOptions) validate() error {
@@ -161,6 +642,26 @@ func (o GitlabOptions) validate() error {
 		return fmt.Errorf("unexpected state: %q", o.State)
 	}
 
+	if err := validateLabels(o.Labels); err != nil {
+		return err
+	}
+	if err := validateLabels(o.NotLabels); err != nil {
+		return err
+	}
+	if strings.ContainsAny(o.AuthorUsername, " \t") {
+		return fmt.Errorf("unexpected author username: %q", o.AuthorUsername)
+	}
+
+	return nil
+}
+
+// validateLabels verifies labels can be sent as a comma separated list.
+func validateLabels(labels []string) error {
+	for _, l := range labels {
+		if strings.TrimSpace(l) == "" || strings.Contains(l, ",") {
+			return fmt.Errorf("unexpected label: %q", l)
+		}
+	}
 	return nil
 }
 
@@ -179,6 +680,27 @@ func (o GitlabOptions) toValues() (url.Values, error) {
 	if o.Sort != "" {
 		v.Set("sort", string(o.Sort))
 	}
+	if len(o.Labels) != 0 {
+		v.Set("labels", strings.Join(o.Labels, ","))
+	}
+	if len(o.NotLabels) != 0 {
+		v.Set("not[labels]", strings.Join(o.NotLabels, ","))
+	}
+	if o.TargetBranch != "" {
+		v.Set("target_branch", o.TargetBranch)
+	}
+	if o.SourceBranch != "" {
+		v.Set("source_branch", o.SourceBranch)
+	}
+	if o.Milestone != "" {
+		v.Set("milestone", o.Milestone)
+	}
+	if o.AuthorUsername != "" {
+		v.Set("author_username", o.AuthorUsername)
+	}
+	if o.Search != "" {
+		v.Set("search", o.Search)
+	}
 	if o.IsDraft != nil {
 		wip := "no"
 		if *o.IsDraft {
@@ -2,8 +2,11 @@ package vcs_test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -35,7 +38,7 @@ func TestNewGitlab(t *testing.T) {
 	})
 }
 
-func TestGitlab_ListPullRequests(t *testing.T) {
+func TestGitlab_ListMergeRequests(t *testing.T) {
 	t.Run("OK", func(t *testing.T) {
 		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
 			assert.Equal(t, http.MethodGet, r.Method)
@@ -58,7 +61,7 @@ func TestGitlab_ListPullRequests(t *testing.T) {
 		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
 		require.NoError(t, err)
 
-		prs, err := gc.ListPullRequests(context.Background(), "1234", vcs.GitlabOptions{
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{
 			Scope:           vcs.ScopeAll,
 			State:           vcs.StateOpened,
 			Sort:            vcs.SortDesc,
@@ -73,27 +76,284 @@ func TestGitlab_ListPullRequests(t *testing.T) {
 				Title: "Add upload",
 				URL:   "https://gitlab.com/group/repo/-/merge_requests/14",
 				Author: preport.Author{
-					Username: "epels",
+					Username:  "epels",
+					Name:      "Emile Pels",
+					AvatarURL: "https://gitlab.com/uploads/-/system/user/avatar/94880/avatar.png",
+					WebURL:    "https://gitlab.com/epels",
 				},
 				CreatedAt: mustParseRFC3339(t, "2019-03-06T14:00:56.380Z"),
+				Project: preport.Project{
+					Path:   "group/repo",
+					Name:   "repo",
+					WebURL: "https://gitlab.com/group/repo",
+				},
+				IID:          14,
+				Labels:       []string{},
+				SourceBranch: "add-upload",
+				TargetBranch: "master",
+				UpdatedAt:    mustParseRFC3339(t, "2019-03-06T14:40:38.551Z"),
 			},
 			{
 				Title: "Strip trailing newlines from log statements.",
 				URL:   "https://gitlab.com/group/repo/-/merge_requests/13",
 				Author: preport.Author{
-					Username: "epels",
+					Username:  "epels",
+					Name:      "Emile Pels",
+					AvatarURL: "https://gitlab.com/uploads/-/system/user/avatar/94880/avatar.png",
+					WebURL:    "https://gitlab.com/epels",
 				},
 				CreatedAt: mustParseRFC3339(t, "2019-03-02T14:54:51.051Z"),
+				Project: preport.Project{
+					Path:   "group/repo",
+					Name:   "repo",
+					WebURL: "https://gitlab.com/group/repo",
+				},
+				IID:          13,
+				Description:  "They are added automatically by the underlaying loggers and are\nthus redundant",
+				Labels:       []string{},
+				SourceBranch: "strip-trailing-newline",
+				TargetBranch: "master",
+				UpdatedAt:    mustParseRFC3339(t, "2019-03-02T14:56:18.675Z"),
 			},
 		}, prs)
 	})
 
+	t.Run("Assignees, labels and comments", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/projects/1234/merge_requests", r.URL.Path)
+			assert.Empty(t, r.URL.Query().Get("assignee_id"))
+
+			testutil.WriteTestdata(t, "testdata/enriched_response.json", w)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, []string{"feature"}, prs[0].Labels)
+		assert.Equal(t, []preport.Author{
+			{
+				Username:  "assignee",
+				Name:      "Assignee",
+				AvatarURL: "https://gitlab.com/uploads/-/system/user/avatar/94880/avatar.png",
+				WebURL:    "https://gitlab.com/assignee",
+			},
+		}, prs[0].Assignees)
+		assert.Equal(t, 2, prs[0].Comments)
+		assert.Empty(t, prs[1].Assignees)
+		assert.Zero(t, prs[1].Comments)
+	})
+
+	t.Run("With approvals", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/projects/1234/merge_requests":
+				testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+			case "/api/v4/projects/10885303/merge_requests/14/approvals":
+				testutil.WriteTestdata(t, "testdata/approvals_response.json", w)
+			case "/api/v4/projects/10885303/merge_requests/13/approvals":
+				_, _ = w.Write([]byte(`{"approved": false, "approvals_left": 2, "approved_by": []}`))
+			default:
+				t.Errorf("Unexpected call to %q", r.URL.Path)
+			}
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{
+			WithApprovals: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, preport.Approval{
+			Approved: true,
+			ApprovedBy: []preport.Author{
+				{
+					Username: "reviewer",
+					Name:     "Reviewer",
+					WebURL:   "https://gitlab.com/reviewer",
+				},
+			},
+			Left: 1,
+		}, prs[0].Approval)
+		assert.Equal(t, preport.Approval{Left: 2}, prs[1].Approval)
+	})
+
+	t.Run("With waiting since", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/projects/1234/merge_requests":
+				testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+			case "/api/v4/projects/10885303/merge_requests/14/notes":
+				assert.Equal(t, "desc", r.URL.Query().Get("sort"))
+				_, _ = w.Write([]byte(`[
+  {"body": "Looks good", "author": {"username": "reviewer"}, "created_at": "2019-03-08T10:00:00Z"},
+  {"body": "requested review from @reviewer", "author": {"username": "assignee"}, "created_at": "2019-03-07T10:00:00Z", "system": true},
+  {"body": "added 1 commit", "author": {"username": "epels"}, "created_at": "2019-03-06T15:00:00Z", "system": true}
+]`))
+			case "/api/v4/projects/10885303/merge_requests/13/notes":
+				// The first page holds no notes by the author, which are
+				// only found on the second page.
+				if r.URL.Query().Get("page") == "" {
+					w.Header().Set("X-Next-Page", "2")
+					_, _ = w.Write([]byte(`[
+  {"body": "Any updates?", "author": {"username": "reviewer"}, "created_at": "2019-03-05T10:00:00Z"}
+]`))
+					return
+				}
+				assert.Equal(t, "2", r.URL.Query().Get("page"))
+				_, _ = w.Write([]byte(`[
+  {"body": "Rebased", "author": {"username": "epels"}, "created_at": "2019-03-04T09:00:00Z"}
+]`))
+			default:
+				t.Errorf("Unexpected call to %q", r.URL.Path)
+			}
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{
+			WithWaitingSince: true,
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, mustParseRFC3339(t, "2019-03-07T10:00:00Z"), prs[0].WaitingSince)
+		assert.Equal(t, mustParseRFC3339(t, "2019-03-04T09:00:00Z"), prs[1].WaitingSince)
+	})
+
+	t.Run("Labels, branches, milestone, author and search", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, url.Values{
+				"labels":          []string{"backend,needs review"},
+				"not[labels]":     []string{"do-not-review"},
+				"target_branch":   []string{"main"},
+				"source_branch":   []string{"feature"},
+				"milestone":       []string{"v1.0"},
+				"author_username": []string{"epels"},
+				"search":          []string{"upload"},
+				"per_page":        []string{"100"},
+			}, r.URL.Query())
+
+			testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		_, err = gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{
+			Labels:         []string{"backend", "needs review"},
+			NotLabels:      []string{"do-not-review"},
+			TargetBranch:   "main",
+			SourceBranch:   "feature",
+			Milestone:      "v1.0",
+			AuthorUsername: "epels",
+			Search:         "upload",
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Invalid options", func(t *testing.T) {
+		gc, err := vcs.NewGitlab("https://example.com", "super-secret")
+		require.NoError(t, err)
+
+		for _, opts := range []vcs.GitlabOptions{
+			{Scope: "mine"},
+			{Sort: "up"},
+			{State: "pending"},
+			{Labels: []string{"a,b"}},
+			{NotLabels: []string{" "}},
+			{AuthorUsername: "john doe"},
+		} {
+			_, err = gc.ListMergeRequests(context.Background(), "1234", opts)
+			assert.Error(t, err, "Expected error for %+v", opts)
+		}
+	})
+
+	t.Run("Multiple pages using X-Next-Page", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/projects/1234/merge_requests", r.URL.Path)
+			assert.Equal(t, "opened", r.URL.Query().Get("state"))
+
+			switch r.URL.Query().Get("page") {
+			case "":
+				w.Header().Set("X-Next-Page", "2")
+				testutil.WriteTestdata(t, "testdata/page_first_response.json", w)
+			case "2":
+				w.Header().Set("X-Next-Page", "")
+				testutil.WriteTestdata(t, "testdata/page_second_response.json", w)
+			default:
+				t.Errorf("Unexpected page %q", r.URL.Query().Get("page"))
+			}
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{
+			State: vcs.StateOpened,
+		})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, "First page", prs[0].Title)
+		assert.Equal(t, "Second page", prs[1].Title)
+	})
+
+	t.Run("Multiple pages using Link", func(t *testing.T) {
+		var serverURL string
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/projects/1234/merge_requests", r.URL.Path)
+
+			switch r.URL.Query().Get("id_after") {
+			case "":
+				w.Header().Set("Link", fmt.Sprintf(`<%s/api/v4/projects/1234/merge_requests?id_after=21&per_page=100>; rel="next"`, serverURL))
+				testutil.WriteTestdata(t, "testdata/page_first_response.json", w)
+			case "21":
+				testutil.WriteTestdata(t, "testdata/page_second_response.json", w)
+			default:
+				t.Errorf("Unexpected id_after %q", r.URL.Query().Get("id_after"))
+			}
+		})
+		serverURL = ts.URL
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, "First page", prs[0].Title)
+		assert.Equal(t, "Second page", prs[1].Title)
+	})
+
+	t.Run("Page limit reached", func(t *testing.T) {
+		var calls int
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.Header().Set("X-Next-Page", strconv.Itoa(calls+1))
+			testutil.WriteTestdata(t, "testdata/page_first_response.json", w)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{
+			MaxPages: 3,
+		})
+		require.NoError(t, err)
+		assert.Len(t, prs, 3)
+		assert.Equal(t, 3, calls)
+	})
+
 	t.Run("Round trip failed", func(t *testing.T) {
 		invalidBaseURL := "https://DF977BEA-4295-4758-AFF9-0EBCB1F509E2.fail"
 		gc, err := vcs.NewGitlab(invalidBaseURL, "super-secret")
 		require.NoError(t, err)
 
-		_, err = gc.ListPullRequests(context.Background(), "1234", vcs.GitlabOptions{})
+		_, err = gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{})
 		require.Error(t, err)
 	})
 
@@ -105,7 +365,232 @@ func TestGitlab_ListPullRequests(t *testing.T) {
 		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
 		require.NoError(t, err)
 
-		_, err = gc.ListPullRequests(context.Background(), "1234", vcs.GitlabOptions{})
+		_, err = gc.ListMergeRequests(context.Background(), "1234", vcs.GitlabOptions{})
+		require.Error(t, err)
+	})
+}
+
+func TestGitlab_ListPullRequests(t *testing.T) {
+	ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v4/projects/1234/merge_requests", r.URL.Path)
+		assert.Equal(t, url.Values{
+			"scope":       []string{"all"},
+			"state":       []string{"opened"},
+			"wip":         []string{"no"},
+			"reviewer_id": []string{"Any"},
+			"sort":        []string{"asc"},
+			"per_page":    []string{"100"},
+		}, r.URL.Query())
+
+		testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+	})
+
+	gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+	require.NoError(t, err)
+
+	prs, err := gc.ListPullRequests(context.Background(), "1234", preport.Filter{
+		IsDraft:     boolPointer(t, false),
+		HasReviewer: boolPointer(t, true),
+	})
+	require.NoError(t, err)
+	assert.Len(t, prs, 2)
+}
+
+func TestGitlab_ListGroupMergeRequests(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/groups/group/merge_requests", r.URL.Path)
+			assert.Equal(t, "opened", r.URL.Query().Get("state"))
+			assert.Equal(t, "Bearer super-secret", r.Header.Get("Authorization"))
+
+			testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListGroupPullRequests(context.Background(), "group", preport.Filter{})
+		require.NoError(t, err)
+		require.Len(t, prs, 2)
+		assert.Equal(t, "group/repo", prs[0].Project.Path)
+	})
+
+	t.Run("Nested group", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/groups/parent%2Fchild/merge_requests", r.URL.EscapedPath())
+
+			testutil.WriteTestdata(t, "testdata/ok_response.json", w)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		prs, err := gc.ListGroupPullRequests(context.Background(), "parent/child", preport.Filter{})
+		require.NoError(t, err)
+		assert.Len(t, prs, 2)
+	})
+}
+
+func TestGitlab_LookupEmail(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v4/users":
+				assert.Equal(t, "epels", r.URL.Query().Get("username"))
+				_, _ = w.Write([]byte(`[{"id": 94880, "username": "epels"}]`))
+			case "/api/v4/users/94880":
+				_, _ = w.Write([]byte(`{"id": 94880, "username": "epels", "public_email": "epels@example.com"}`))
+			default:
+				t.Errorf("Unexpected call to %q", r.URL.Path)
+			}
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		email, err := gc.LookupEmail(context.Background(), "epels")
+		require.NoError(t, err)
+		assert.Equal(t, "epels@example.com", email)
+	})
+
+	t.Run("User not found", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[]`))
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		_, err = gc.LookupEmail(context.Background(), "epels")
+		require.Error(t, err)
+	})
+}
+
+func TestGitlab_AssignReviewer(t *testing.T) {
+	ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/api/v4/users":
+			assert.Equal(t, "reviewer", r.URL.Query().Get("username"))
+			_, _ = w.Write([]byte(`[{"id": 42, "username": "reviewer"}]`))
+		case "/api/v4/projects/group%2Frepo/merge_requests/14":
+			assert.Equal(t, http.MethodPut, r.Method)
+			assert.Equal(t, url.Values{"reviewer_ids[]": {"42"}}, r.URL.Query())
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("Unexpected call to %q", r.URL.EscapedPath())
+		}
+	})
+
+	gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+	require.NoError(t, err)
+
+	err = gc.AssignReviewer(context.Background(), preport.PullRequest{
+		Project: preport.Project{Path: "group/repo"},
+		IID:     14,
+	}, "reviewer")
+	require.NoError(t, err)
+}
+
+func TestGitlab_CountReviews(t *testing.T) {
+	t.Run("Total", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v4/merge_requests", r.URL.Path)
+			assert.Equal(t, "reviewer", r.URL.Query().Get("reviewer_username"))
+			assert.Equal(t, "opened", r.URL.Query().Get("state"))
+			w.Header().Set("X-Total", "123")
+			_, _ = w.Write([]byte(`[{}, {}]`))
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		n, err := gc.CountReviews(context.Background(), "reviewer")
+		require.NoError(t, err)
+		assert.Equal(t, 123, n)
+	})
+
+	t.Run("Without total", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{}, {}]`))
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		n, err := gc.CountReviews(context.Background(), "reviewer")
+		require.NoError(t, err)
+		assert.Equal(t, 2, n)
+	})
+}
+
+func TestGitlab_SuggestOwners(t *testing.T) {
+	pr := preport.PullRequest{
+		Author:       preport.Author{Username: "epels"},
+		Project:      preport.Project{Path: "group/repo"},
+		IID:          14,
+		TargetBranch: "main",
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		var fileCalls int
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.EscapedPath() {
+			case "/api/v4/projects/group%2Frepo/repository/files/CODEOWNERS/raw":
+				fileCalls++
+				w.WriteHeader(http.StatusNotFound)
+			case "/api/v4/projects/group%2Frepo/repository/files/docs%2FCODEOWNERS/raw":
+				fileCalls++
+				assert.Equal(t, "main", r.URL.Query().Get("ref"))
+				testutil.WriteTestdata(t, "testdata/CODEOWNERS", w)
+			case "/api/v4/projects/group%2Frepo/merge_requests/14/diffs":
+				testutil.WriteTestdata(t, "testdata/diffs_response.json", w)
+			default:
+				t.Errorf("Unexpected call to %q", r.URL.EscapedPath())
+			}
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			owners, err := gc.SuggestOwners(context.Background(), pr)
+			require.NoError(t, err)
+			assert.Equal(t, []preport.Author{
+				{Username: "maintainers/backend"},
+				{Username: "writer"},
+				{Username: "lead"},
+				{Username: "frontend"},
+			}, owners)
+		}
+		// The CODEOWNERS file is only fetched once per project and branch.
+		assert.Equal(t, 2, fileCalls)
+	})
+
+	t.Run("No CODEOWNERS", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if strings.HasSuffix(r.URL.Path, "/diffs") {
+				t.Errorf("Unexpected call to %q", r.URL.Path)
+			}
+			w.WriteHeader(http.StatusNotFound)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		owners, err := gc.SuggestOwners(context.Background(), pr)
+		require.NoError(t, err)
+		assert.Empty(t, owners)
+	})
+
+	t.Run("Unexpected status code", func(t *testing.T) {
+		ts := testutil.NewTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		gc, err := vcs.NewGitlab(ts.URL, "super-secret")
+		require.NoError(t, err)
+
+		_, err = gc.SuggestOwners(context.Background(), pr)
 		require.Error(t, err)
 	})
 }
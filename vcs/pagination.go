@@ -0,0 +1,48 @@
+package vcs
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// nextPageURL returns the URL of the page following u, based on the pagination
+// headers of its response. The X-Next-Page header used by offset-based
+// pagination takes precedence over the Link header used by keyset-based
+// pagination. An empty string is returned when there is no next page.
+func nextPageURL(u *url.URL, h http.Header) (string, error) {
+	if page := h.Get("X-Next-Page"); page != "" {
+		next := *u
+		q := next.Query()
+		q.Set("page", page)
+		next.RawQuery = q.Encode()
+		return next.String(), nil
+	}
+
+	for _, link := range h.Values("Link") {
+		for _, l := range strings.Split(link, ",") {
+			parts := strings.Split(l, ";")
+			if len(parts) < 2 {
+				continue
+			}
+			isNext := false
+			for _, p := range parts[1:] {
+				if strings.TrimSpace(p) == `rel="next"` {
+					isNext = true
+				}
+			}
+			if !isNext {
+				continue
+			}
+
+			ref := strings.Trim(strings.TrimSpace(parts[0]), "<>")
+			next, err := u.Parse(ref)
+			if err != nil {
+				return "", fmt.Errorf("net/url: URL.Parse: %s", err)
+			}
+			return next.String(), nil
+		}
+	}
+	return "", nil
+}